@@ -0,0 +1,49 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
+)
+
+//serviceScalableTarget Return the Application Auto Scaling resource id of an ECS service
+func serviceScalableTarget(cluster, serviceName string) string {
+	// the cluster may be given by ARN
+	if i := strings.LastIndex(cluster, "/"); i >= 0 {
+		cluster = cluster[i+1:]
+	}
+	return fmt.Sprintf("service/%s/%s", cluster, serviceName)
+}
+
+//GetServiceMinCapacity Return the minimum capacity of the scalable target
+//of an ECS service, false when the service has no auto scaling
+func (awsSession *AWSSession) GetServiceMinCapacity(cluster, serviceName string) (int64, bool, error) {
+	svc := applicationautoscaling.New(awsSession.Client)
+	result, err := svc.DescribeScalableTargets(&applicationautoscaling.DescribeScalableTargetsInput{
+		ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceEcs),
+		ScalableDimension: aws.String(applicationautoscaling.ScalableDimensionEcsServiceDesiredCount),
+		ResourceIds:       aws.StringSlice([]string{serviceScalableTarget(cluster, serviceName)}),
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if len(result.ScalableTargets) == 0 {
+		return 0, false, nil
+	}
+	return aws.Int64Value(result.ScalableTargets[0].MinCapacity), true, nil
+}
+
+//SetServiceMinCapacity change the minimum capacity of the scalable target of an ECS service,
+//its maximum capacity and scaling policies are kept
+func (awsSession *AWSSession) SetServiceMinCapacity(cluster, serviceName string, minCapacity int64) error {
+	svc := applicationautoscaling.New(awsSession.Client)
+	_, err := svc.RegisterScalableTarget(&applicationautoscaling.RegisterScalableTargetInput{
+		ServiceNamespace:  aws.String(applicationautoscaling.ServiceNamespaceEcs),
+		ScalableDimension: aws.String(applicationautoscaling.ScalableDimensionEcsServiceDesiredCount),
+		ResourceId:        aws.String(serviceScalableTarget(cluster, serviceName)),
+		MinCapacity:       aws.Int64(minCapacity),
+	})
+	return err
+}
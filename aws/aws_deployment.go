@@ -0,0 +1,124 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+//MaxFailedTasks is the number of tasks that may fail to start
+//before a deployment is considered failed.
+//ECS only counts them (FailedTasks) when the deployment circuit breaker is enabled,
+//the failed tasks of the deployment are counted by CountFailedTasks otherwise.
+const MaxFailedTasks int64 = 3
+
+//ServiceDeploymentState summarize the rollout of a service
+//as seen from its deployments list.
+type ServiceDeploymentState struct {
+	DeploymentID string
+	Deployments  int
+	RolloutState string
+	DesiredCount int64
+	RunningCount int64
+	PendingCount int64
+	FailedTasks  int64
+	Stable       bool
+	Failed       bool
+	Reason       string
+}
+
+//DescribeAWSService Return the ECS description of a single service
+func (awsSession *AWSSession) DescribeAWSService(svc *ecs.ECS, serviceName, serviceCluster *string) (*ecs.Service, error) {
+	input := &ecs.DescribeServicesInput{
+		Cluster:  serviceCluster,
+		Services: []*string{serviceName},
+	}
+
+	result, err := awsMust(svc.DescribeServices(input))
+	if err != nil {
+		return nil, err
+	}
+
+	if services := result.(*ecs.DescribeServicesOutput).Services; len(services) > 0 {
+		return services[0], nil
+	}
+	return nil, fmt.Errorf("service %s not found on cluster %s", *serviceName, *serviceCluster)
+}
+
+//DeploymentState compute the rollout state of service.
+//taskDefinition (family:revision) is the revision expected on the PRIMARY deployment,
+//leave it empty to accept whatever revision is PRIMARY.
+func DeploymentState(service *ecs.Service, taskDefinition string) *ServiceDeploymentState {
+	state := &ServiceDeploymentState{
+		Deployments: len(service.Deployments),
+	}
+
+	var primary *ecs.Deployment
+	for _, d := range service.Deployments {
+		if strings.EqualFold("PRIMARY", aws.StringValue(d.Status)) {
+			primary = d
+			break
+		}
+	}
+
+	if primary == nil {
+		state.Reason = "no PRIMARY deployment"
+		return state
+	}
+
+	state.DeploymentID = aws.StringValue(primary.Id)
+	state.RolloutState = aws.StringValue(primary.RolloutState)
+	state.DesiredCount = aws.Int64Value(primary.DesiredCount)
+	state.RunningCount = aws.Int64Value(primary.RunningCount)
+	state.PendingCount = aws.Int64Value(primary.PendingCount)
+	state.FailedTasks = aws.Int64Value(primary.FailedTasks)
+
+	if !strings.EqualFold("", taskDefinition) && !IsSameTaskDefinition(aws.StringValue(primary.TaskDefinition), taskDefinition) {
+		state.Failed = true
+		state.Reason = fmt.Sprintf("PRIMARY deployment uses %s", aws.StringValue(primary.TaskDefinition))
+		return state
+	}
+
+	if strings.EqualFold(ecs.DeploymentRolloutStateFailed, state.RolloutState) {
+		state.Failed = true
+		state.Reason = aws.StringValue(primary.RolloutStateReason)
+		return state
+	}
+
+	if state.FailedTasks >= MaxFailedTasks {
+		state.Failed = true
+		state.Reason = fmt.Sprintf("%d tasks failed to start", state.FailedTasks)
+		return state
+	}
+
+	if state.Deployments == 1 && state.RunningCount == state.DesiredCount && state.PendingCount == 0 &&
+		(strings.EqualFold("", state.RolloutState) || strings.EqualFold(ecs.DeploymentRolloutStateCompleted, state.RolloutState)) {
+		state.Stable = true
+	}
+
+	return state
+}
+
+//StoppedTasks record the tasks of the deployment that failed (CountFailedTasks),
+//the deployment fails once MaxFailedTasks is reached, whether or not ECS counted them
+func (state *ServiceDeploymentState) StoppedTasks(stopped int64) {
+	if state.Stable || state.Failed || stopped <= state.FailedTasks {
+		return
+	}
+	state.FailedTasks = stopped
+	if stopped >= MaxFailedTasks {
+		state.Failed = true
+		state.Reason = fmt.Sprintf("%d tasks stopped", stopped)
+	}
+}
+
+//IsSameTaskDefinition compare a task definition ARN with a family:revision
+//(or another ARN)
+func IsSameTaskDefinition(taskDefinitionARN, taskDefinition string) bool {
+	if strings.EqualFold(taskDefinitionARN, taskDefinition) {
+		return true
+	}
+	return strings.HasSuffix(taskDefinitionARN, fmt.Sprintf("/%s", taskDefinition))
+}
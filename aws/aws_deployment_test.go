@@ -0,0 +1,143 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+const testTaskDefinitionArn = "arn:aws:ecs:eu-west-1:123456789012:task-definition/hapi:12"
+
+//testDeployment is a deployment of hapi:revision running running tasks out of desired
+func testDeployment(status, revision string, desired, running, pending int64) *ecs.Deployment {
+	return &ecs.Deployment{
+		Id:             aws.String("ecs-svc/" + revision),
+		Status:         aws.String(status),
+		TaskDefinition: aws.String("arn:aws:ecs:eu-west-1:123456789012:task-definition/hapi:" + revision),
+		DesiredCount:   aws.Int64(desired),
+		RunningCount:   aws.Int64(running),
+		PendingCount:   aws.Int64(pending),
+	}
+}
+
+func TestDeploymentState(t *testing.T) {
+	rolledOut := testDeployment("PRIMARY", "12", 2, 2, 0)
+	rolledOut.RolloutState = aws.String(ecs.DeploymentRolloutStateCompleted)
+
+	failedRollout := testDeployment("PRIMARY", "12", 2, 0, 0)
+	failedRollout.RolloutState = aws.String(ecs.DeploymentRolloutStateFailed)
+	failedRollout.RolloutStateReason = aws.String("circuit breaker triggered")
+
+	failedTasks := testDeployment("PRIMARY", "12", 2, 0, 1)
+	failedTasks.FailedTasks = aws.Int64(MaxFailedTasks)
+
+	tests := []struct {
+		name           string
+		deployments    []*ecs.Deployment
+		taskDefinition string
+		stable         bool
+		failed         bool
+		reason         string
+	}{
+		{
+			name:           "stable",
+			deployments:    []*ecs.Deployment{testDeployment("PRIMARY", "12", 2, 2, 0)},
+			taskDefinition: "hapi:12",
+			stable:         true,
+		},
+		{
+			name:           "rollout completed, expected revision given by ARN",
+			deployments:    []*ecs.Deployment{rolledOut},
+			taskDefinition: testTaskDefinitionArn,
+			stable:         true,
+		},
+		{
+			name:        "any revision accepted",
+			deployments: []*ecs.Deployment{testDeployment("PRIMARY", "11", 1, 1, 0)},
+			stable:      true,
+		},
+		{
+			name:           "PRIMARY runs another revision",
+			deployments:    []*ecs.Deployment{testDeployment("PRIMARY", "13", 2, 2, 0)},
+			taskDefinition: "hapi:12",
+			failed:         true,
+			reason:         "PRIMARY deployment uses arn:aws:ecs:eu-west-1:123456789012:task-definition/hapi:13",
+		},
+		{
+			name:           "rollout FAILED",
+			deployments:    []*ecs.Deployment{failedRollout, testDeployment("ACTIVE", "11", 2, 2, 0)},
+			taskDefinition: "hapi:12",
+			failed:         true,
+			reason:         "circuit breaker triggered",
+		},
+		{
+			name:           "failed tasks counted by ECS",
+			deployments:    []*ecs.Deployment{failedTasks, testDeployment("ACTIVE", "11", 2, 2, 0)},
+			taskDefinition: "hapi:12",
+			failed:         true,
+			reason:         "3 tasks failed to start",
+		},
+		{
+			name:           "tasks pending",
+			deployments:    []*ecs.Deployment{testDeployment("PRIMARY", "12", 2, 1, 1)},
+			taskDefinition: "hapi:12",
+		},
+		{
+			name:           "old deployment still running",
+			deployments:    []*ecs.Deployment{testDeployment("PRIMARY", "12", 2, 2, 0), testDeployment("ACTIVE", "11", 2, 1, 0)},
+			taskDefinition: "hapi:12",
+		},
+		{
+			name:           "no PRIMARY deployment",
+			deployments:    []*ecs.Deployment{testDeployment("ACTIVE", "11", 2, 2, 0)},
+			taskDefinition: "hapi:12",
+			reason:         "no PRIMARY deployment",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := DeploymentState(&ecs.Service{Deployments: test.deployments}, test.taskDefinition)
+			if state.Stable != test.stable || state.Failed != test.failed {
+				t.Errorf("expected stable %t failed %t, got stable %t failed %t (%s)", test.stable, test.failed, state.Stable, state.Failed, state.Reason)
+			}
+			if state.Reason != test.reason {
+				t.Errorf("expected reason %q, got %q", test.reason, state.Reason)
+			}
+			if state.Deployments != len(test.deployments) {
+				t.Errorf("expected %d deployments, got %d", len(test.deployments), state.Deployments)
+			}
+		})
+	}
+}
+
+func TestDeploymentStateStoppedTasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		running int64
+		stopped int64
+		failed  bool
+	}{
+		{name: "no stopped task", running: 0, stopped: 0},
+		{name: "below the limit", running: 0, stopped: MaxFailedTasks - 1},
+		{name: "tasks keep stopping", running: 0, stopped: MaxFailedTasks, failed: true},
+		{name: "stable deployment", running: 2, stopped: MaxFailedTasks},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := DeploymentState(&ecs.Service{Deployments: []*ecs.Deployment{
+				testDeployment("PRIMARY", "12", 2, test.running, 0),
+			}}, "hapi:12")
+			if state.DeploymentID != "ecs-svc/12" {
+				t.Errorf("expected deployment ecs-svc/12, got %s", state.DeploymentID)
+			}
+
+			state.StoppedTasks(test.stopped)
+			if state.Failed != test.failed {
+				t.Errorf("expected failed %t, got %t (%s)", test.failed, state.Failed, state.Reason)
+			}
+		})
+	}
+}
@@ -59,7 +59,9 @@ func (awsSession *AWSSession) GetDockerAuthStrFromEcr(svc *ecr.ECR, RegistryId *
 
 	tokenOutput, err := awsMust(svc.GetAuthorizationToken(&tokenInput))
 	if err != nil {
-		log.Fatal(err)
+		// the push is reported as failed, the deploy goes on
+		log.Println(err)
+		return aws.String("")
 	}
 	decodedToken, err := base64.StdEncoding.DecodeString(aws.StringValue(tokenOutput.(*ecr.GetAuthorizationTokenOutput).AuthorizationData[0].AuthorizationToken))
 	if err != nil {
@@ -107,7 +109,8 @@ func (awsSession *AWSSession) PushToECR(repositoryName, repositoryTag, fullURI s
 
 			out, err := cli.ImagePush(ctx, fullURI, types.ImagePushOptions{RegistryAuth: aws.StringValue(authStr)})
 			if err != nil {
-				panic(err)
+				log.Println(err)
+				return false
 			}
 			defer out.Close()
 
@@ -121,3 +124,56 @@ func (awsSession *AWSSession) PushToECR(repositoryName, repositoryTag, fullURI s
 	}
 	return false
 }
+
+//IsECRImage tell if image is hosted on an ECR registry
+func IsECRImage(image string) bool {
+	host := strings.SplitN(image, "/", 2)[0]
+	return strings.Contains(host, ".dkr.ecr.") && strings.Contains(host, ".amazonaws.com")
+}
+
+//ECRImageDigest Return the digest of the ECR image, empty when it does not exist.
+//image is <account>.dkr.ecr.<region>.amazonaws.com/repository[:tag][@digest]
+func (awsSession *AWSSession) ECRImageDigest(image string) (string, error) {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid ECR image %s", image)
+	}
+	host := strings.Split(parts[0], ".")
+	if len(host) < 4 {
+		return "", fmt.Errorf("invalid ECR registry %s", parts[0])
+	}
+	registryID, region := host[0], host[3]
+
+	repository := parts[1]
+	imageID := &ecr.ImageIdentifier{ImageTag: aws.String("latest")}
+	if i := strings.Index(repository, "@"); i >= 0 {
+		imageID = &ecr.ImageIdentifier{ImageDigest: aws.String(repository[i+1:])}
+		repository = repository[:i]
+	}
+	if i := strings.LastIndex(repository, ":"); i >= 0 {
+		if imageID.ImageDigest == nil {
+			imageID = &ecr.ImageIdentifier{ImageTag: aws.String(repository[i+1:])}
+		}
+		repository = repository[:i]
+	}
+
+	svc := ecr.New(awsSession.Client, aws.NewConfig().WithRegion(region))
+	result, err := svc.DescribeImages(&ecr.DescribeImagesInput{
+		RegistryId:     aws.String(registryID),
+		RepositoryName: aws.String(repository),
+		ImageIds:       []*ecr.ImageIdentifier{imageID},
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case ecr.ErrCodeImageNotFoundException, ecr.ErrCodeRepositoryNotFoundException:
+				return "", nil
+			}
+		}
+		return "", err
+	}
+	if len(result.ImageDetails) == 0 {
+		return "", nil
+	}
+	return aws.StringValue(result.ImageDetails[0].ImageDigest), nil
+}
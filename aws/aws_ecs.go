@@ -1,10 +1,13 @@
 package aws
 
 import (
+	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/gpkfr/goretdep/config"
 )
@@ -16,10 +19,14 @@ func (awsSession *AWSSession) GetSVC() {
 func (awsSession *AWSSession) GetCurrentTaskDefinition(svc *ecs.ECS, taskARN string) (*ecs.DescribeTaskDefinitionOutput, error) {
 	input := &ecs.DescribeTaskDefinitionInput{
 		TaskDefinition: aws.String(taskARN),
+		Include:        aws.StringSlice([]string{ecs.TaskDefinitionFieldTags}),
 	}
 
 	result, err := awsMust(svc.DescribeTaskDefinition(input))
-	return result.(*ecs.DescribeTaskDefinitionOutput), err
+	if err != nil {
+		return nil, err
+	}
+	return result.(*ecs.DescribeTaskDefinitionOutput), nil
 }
 
 func (awsSession *AWSSession) UpdateAWSService(svc *ecs.ECS, serviceName, serviceCluster, taskDefinition *string, forceDeploy bool) (*ecs.UpdateServiceOutput, error) {
@@ -30,7 +37,96 @@ func (awsSession *AWSSession) UpdateAWSService(svc *ecs.ECS, serviceName, servic
 		TaskDefinition:     taskDefinition,
 	}
 	result, err := awsMust(svc.UpdateService(input))
-	return result.(*ecs.UpdateServiceOutput), err
+	if err != nil {
+		return nil, err
+	}
+	return result.(*ecs.UpdateServiceOutput), nil
+}
+
+//SetDesiredCount update the number of tasks a service runs
+func (awsSession *AWSSession) SetDesiredCount(svc *ecs.ECS, serviceName, serviceCluster *string, desiredCount int64) (*ecs.UpdateServiceOutput, error) {
+	input := &ecs.UpdateServiceInput{
+		Cluster:      serviceCluster,
+		Service:      serviceName,
+		DesiredCount: aws.Int64(desiredCount),
+	}
+	result, err := awsMust(svc.UpdateService(input))
+	if err != nil {
+		return nil, err
+	}
+	return result.(*ecs.UpdateServiceOutput), nil
+}
+
+//GetResourceTag Return the value of the tag key of an ECS resource (service, task definition...)
+func (awsSession *AWSSession) GetResourceTag(svc *ecs.ECS, resourceArn *string, key string) (string, bool, error) {
+	result, err := awsMust(svc.ListTagsForResource(&ecs.ListTagsForResourceInput{ResourceArn: resourceArn}))
+	if err != nil {
+		return "", false, err
+	}
+	for _, tag := range result.(*ecs.ListTagsForResourceOutput).Tags {
+		if aws.StringValue(tag.Key) == key {
+			return aws.StringValue(tag.Value), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+//SetResourceTag add or replace the tag key of an ECS resource
+func (awsSession *AWSSession) SetResourceTag(svc *ecs.ECS, resourceArn *string, key, value string) error {
+	_, err := awsMust(svc.TagResource(&ecs.TagResourceInput{
+		ResourceArn: resourceArn,
+		Tags:        []*ecs.Tag{{Key: aws.String(key), Value: aws.String(value)}},
+	}))
+	return err
+}
+
+//RemoveResourceTag remove the tag key of an ECS resource
+func (awsSession *AWSSession) RemoveResourceTag(svc *ecs.ECS, resourceArn *string, key string) error {
+	_, err := awsMust(svc.UntagResource(&ecs.UntagResourceInput{
+		ResourceArn: resourceArn,
+		TagKeys:     aws.StringSlice([]string{key}),
+	}))
+	return err
+}
+
+//NewRegisterTaskDefinitionInput clone every field of taskDefinition (and its tags)
+//into the input registering its next revision
+func NewRegisterTaskDefinitionInput(taskDefinition *ecs.TaskDefinition, tags []*ecs.Tag) *ecs.RegisterTaskDefinitionInput {
+	clone := awsutil.CopyOf(taskDefinition).(*ecs.TaskDefinition)
+
+	input := &ecs.RegisterTaskDefinitionInput{
+		ContainerDefinitions:    clone.ContainerDefinitions,
+		Cpu:                     clone.Cpu,
+		EphemeralStorage:        clone.EphemeralStorage,
+		ExecutionRoleArn:        clone.ExecutionRoleArn,
+		Family:                  clone.Family,
+		InferenceAccelerators:   clone.InferenceAccelerators,
+		IpcMode:                 clone.IpcMode,
+		Memory:                  clone.Memory,
+		NetworkMode:             clone.NetworkMode,
+		PidMode:                 clone.PidMode,
+		PlacementConstraints:    clone.PlacementConstraints,
+		ProxyConfiguration:      clone.ProxyConfiguration,
+		RequiresCompatibilities: clone.RequiresCompatibilities,
+		RuntimePlatform:         clone.RuntimePlatform,
+		TaskRoleArn:             clone.TaskRoleArn,
+		Volumes:                 clone.Volumes,
+	}
+
+	// an empty tag list is rejected by the API
+	if len(tags) > 0 {
+		input.Tags = awsutil.CopyOf(tags).([]*ecs.Tag)
+	}
+	return input
+}
+
+//DeregisterAWSTaskDefinition mark a task definition revision INACTIVE
+func (awsSession *AWSSession) DeregisterAWSTaskDefinition(svc *ecs.ECS, taskDefinition *string) error {
+	input := &ecs.DeregisterTaskDefinitionInput{
+		TaskDefinition: taskDefinition,
+	}
+	_, err := awsMust(svc.DeregisterTaskDefinition(input))
+	return err
 }
 
 func (awsSession *AWSSession) GetServices(services *config.Services, repos *config.Repositories, child *config.ChildTasks, env string, isDeploy bool, selectedServices ...string) {
@@ -60,8 +156,192 @@ func (awsSession *AWSSession) GetServices(services *config.Services, repos *conf
 			currentTask, err := awsSession.GetCurrentTaskDefinition(awsSession.Svc, s.TaskARN)
 			if err != nil {
 				log.Println(err)
+				continue
 			}
 			services.Services[i].TaskDefinition = currentTask.TaskDefinition
+			services.Services[i].TaskDefinitionTags = currentTask.Tags
+		}
+	}
+}
+
+//ListTaskDefinitionRevisions Return the last ACTIVE revisions of a task definition family,
+//newest first.
+func (awsSession *AWSSession) ListTaskDefinitionRevisions(svc *ecs.ECS, family string, maxRevisions int64) ([]*ecs.TaskDefinition, error) {
+	arns, err := awsSession.ListTaskDefinitionArns(svc, family, maxRevisions)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]*ecs.TaskDefinition, 0, len(arns))
+	for _, arn := range arns {
+		taskDefinition, err := awsSession.GetCurrentTaskDefinition(svc, arn)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, taskDefinition.TaskDefinition)
+	}
+	return revisions, nil
+}
+
+//ListTaskDefinitionArns Return the ARNs of the last ACTIVE revisions of a task definition family,
+//newest first. Every revision is returned when maxRevisions is 0.
+func (awsSession *AWSSession) ListTaskDefinitionArns(svc *ecs.ECS, family string, maxRevisions int64) ([]string, error) {
+	input := &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(family),
+		Status:       aws.String(ecs.TaskDefinitionStatusActive),
+		Sort:         aws.String(ecs.SortOrderDesc),
+	}
+
+	arns := make([]string, 0, maxRevisions)
+	err := svc.ListTaskDefinitionsPages(input, func(page *ecs.ListTaskDefinitionsOutput, lastPage bool) bool {
+		for _, arn := range page.TaskDefinitionArns {
+			// FamilyPrefix also match families sharing the same prefix
+			if strings.EqualFold(TaskDefinitionFamily(*arn), family) {
+				arns = append(arns, *arn)
+			}
+			if maxRevisions > 0 && int64(len(arns)) >= maxRevisions {
+				return false
+			}
+		}
+		return !lastPage
+	})
+	if _, err := awsMust(nil, err); err != nil {
+		return nil, err
+	}
+	return arns, nil
+}
+
+//PreviousTaskDefinitionArn Return the ARN of the newest ACTIVE revision of a task definition family
+//older than revision, or an empty string when there is none.
+func (awsSession *AWSSession) PreviousTaskDefinitionArn(svc *ecs.ECS, family string, revision int64) (string, error) {
+	input := &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(family),
+		Status:       aws.String(ecs.TaskDefinitionStatusActive),
+		Sort:         aws.String(ecs.SortOrderDesc),
+	}
+
+	var previous string
+	err := svc.ListTaskDefinitionsPages(input, func(page *ecs.ListTaskDefinitionsOutput, lastPage bool) bool {
+		for _, arn := range page.TaskDefinitionArns {
+			if !strings.EqualFold(TaskDefinitionFamily(*arn), family) {
+				continue
+			}
+			if TaskDefinitionRevision(*arn) < revision {
+				previous = *arn
+				return false
+			}
+		}
+		return !lastPage
+	})
+	if _, err := awsMust(nil, err); err != nil {
+		return "", err
+	}
+	return previous, nil
+}
+
+//TaskDefinitionsInUse Return the task definition ARNs used by the deployments
+//of the services and by the tasks running in cluster
+func (awsSession *AWSSession) TaskDefinitionsInUse(svc *ecs.ECS, cluster string) (map[string]bool, error) {
+	inUse := make(map[string]bool)
+
+	serviceArns := make([]*string, 0)
+	err := svc.ListServicesPages(&ecs.ListServicesInput{Cluster: aws.String(cluster)}, func(page *ecs.ListServicesOutput, lastPage bool) bool {
+		serviceArns = append(serviceArns, page.ServiceArns...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+	// DescribeServices accepts 10 services at most
+	for i := 0; i < len(serviceArns); i += 10 {
+		end := i + 10
+		if end > len(serviceArns) {
+			end = len(serviceArns)
+		}
+		result, err := svc.DescribeServices(&ecs.DescribeServicesInput{Cluster: aws.String(cluster), Services: serviceArns[i:end]})
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range result.Services {
+			inUse[aws.StringValue(service.TaskDefinition)] = true
+			for _, deployment := range service.Deployments {
+				inUse[aws.StringValue(deployment.TaskDefinition)] = true
+			}
+		}
+	}
+
+	taskArns := make([]*string, 0)
+	err = svc.ListTasksPages(&ecs.ListTasksInput{Cluster: aws.String(cluster)}, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+		taskArns = append(taskArns, page.TaskArns...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+	// DescribeTasks accepts 100 tasks at most
+	for i := 0; i < len(taskArns); i += 100 {
+		end := i + 100
+		if end > len(taskArns) {
+			end = len(taskArns)
+		}
+		result, err := svc.DescribeTasks(&ecs.DescribeTasksInput{Cluster: aws.String(cluster), Tasks: taskArns[i:end]})
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range result.Tasks {
+			inUse[aws.StringValue(task.TaskDefinitionArn)] = true
+		}
+	}
+
+	return inUse, nil
+}
+
+//TaskDefinitionFamily extract the family from a task definition ARN
+//or a family:revision string
+func TaskDefinitionFamily(taskDefinition string) string {
+	if i := strings.LastIndex(taskDefinition, "/"); i >= 0 {
+		taskDefinition = taskDefinition[i+1:]
+	}
+	return strings.SplitN(taskDefinition, ":", 2)[0]
+}
+
+//TaskDefinitionRevision Return the revision of a task definition ARN or family:revision,
+//0 when it has none
+func TaskDefinitionRevision(taskDefinition string) int64 {
+	i := strings.LastIndex(taskDefinition, ":")
+	if i < 0 {
+		return 0
+	}
+	revision, err := strconv.ParseInt(taskDefinition[i+1:], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+//SelectContainerDefinitions Return the container definitions named,
+//or the first container definition when no name is given.
+func SelectContainerDefinitions(containers []*ecs.ContainerDefinition, names ...string) ([]*ecs.ContainerDefinition, error) {
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("task definition has no container")
+	}
+	if len(names) == 0 {
+		return containers[:1], nil
+	}
+
+	selected := make([]*ecs.ContainerDefinition, 0, len(names))
+	for _, name := range names {
+		var found bool
+		for _, container := range containers {
+			if strings.EqualFold(aws.StringValue(container.Name), name) {
+				selected = append(selected, container)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("container %s not found", name)
 		}
 	}
+	return selected, nil
 }
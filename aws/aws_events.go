@@ -0,0 +1,117 @@
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/scheduler"
+)
+
+//ScheduledTaskDefinitions Return the task definitions targeted by
+//the CloudWatch Events (EventBridge) rules of every event bus and by
+//EventBridge Scheduler schedules, as ARN or family[:revision].
+//Any listing error is returned: an incomplete list is not safe to prune with.
+func (awsSession *AWSSession) ScheduledTaskDefinitions() (map[string]bool, error) {
+	scheduled := make(map[string]bool)
+	if err := awsSession.ruleTaskDefinitions(scheduled); err != nil {
+		return nil, err
+	}
+	if err := awsSession.scheduleTaskDefinitions(scheduled); err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+//ruleTaskDefinitions add the task definitions targeted by the rules of every event bus
+func (awsSession *AWSSession) ruleTaskDefinitions(scheduled map[string]bool) error {
+	svc := cloudwatchevents.New(awsSession.Client)
+
+	var busToken *string
+	for {
+		buses, err := svc.ListEventBuses(&cloudwatchevents.ListEventBusesInput{NextToken: busToken})
+		if err != nil {
+			return err
+		}
+
+		for _, bus := range buses.EventBuses {
+			var nextToken *string
+			for {
+				rules, err := svc.ListRules(&cloudwatchevents.ListRulesInput{EventBusName: bus.Name, NextToken: nextToken})
+				if err != nil {
+					return err
+				}
+
+				for _, rule := range rules.Rules {
+					var targetsToken *string
+					for {
+						targets, err := svc.ListTargetsByRule(&cloudwatchevents.ListTargetsByRuleInput{
+							Rule:         rule.Name,
+							EventBusName: bus.Name,
+							NextToken:    targetsToken,
+						})
+						if err != nil {
+							return err
+						}
+						for _, target := range targets.Targets {
+							if target.EcsParameters != nil {
+								scheduled[aws.StringValue(target.EcsParameters.TaskDefinitionArn)] = true
+							}
+						}
+						if targets.NextToken == nil {
+							break
+						}
+						targetsToken = targets.NextToken
+					}
+				}
+
+				if rules.NextToken == nil {
+					break
+				}
+				nextToken = rules.NextToken
+			}
+		}
+
+		if buses.NextToken == nil {
+			break
+		}
+		busToken = buses.NextToken
+	}
+	return nil
+}
+
+//scheduleTaskDefinitions add the task definitions run by EventBridge Scheduler schedules
+func (awsSession *AWSSession) scheduleTaskDefinitions(scheduled map[string]bool) error {
+	svc := scheduler.New(awsSession.Client)
+
+	var nextToken *string
+	for {
+		schedules, err := svc.ListSchedules(&scheduler.ListSchedulesInput{NextToken: nextToken})
+		if err != nil {
+			return err
+		}
+
+		for _, summary := range schedules.Schedules {
+			// only ECS targets (a cluster ARN) carry a task definition
+			if summary.Target == nil || !strings.Contains(aws.StringValue(summary.Target.Arn), ":ecs:") {
+				continue
+			}
+			schedule, err := svc.GetSchedule(&scheduler.GetScheduleInput{
+				Name:      summary.Name,
+				GroupName: summary.GroupName,
+			})
+			if err != nil {
+				return err
+			}
+			if schedule.Target != nil && schedule.Target.EcsParameters != nil {
+				scheduled[aws.StringValue(schedule.Target.EcsParameters.TaskDefinitionArn)] = true
+			}
+		}
+
+		if schedules.NextToken == nil {
+			break
+		}
+		nextToken = schedules.NextToken
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/gpkfr/goretdep/gtdhistory"
+)
+
+//S3HistoryStore keep one json object per record under Bucket/Prefix/env/
+type S3HistoryStore struct {
+	svc    *s3.S3
+	Bucket string
+	Prefix string
+}
+
+//NewS3HistoryStore Return a history store on bucket
+func (awsSession *AWSSession) NewS3HistoryStore(bucket, prefix string) *S3HistoryStore {
+	return &S3HistoryStore{
+		svc:    s3.New(awsSession.Client),
+		Bucket: bucket,
+		Prefix: prefix,
+	}
+}
+
+//GetCallerIdentity Return the ARN of the AWS identity in use
+func (awsSession *AWSSession) GetCallerIdentity() (string, error) {
+	svc := sts.New(awsSession.Client)
+
+	result, err := svc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(result.Arn), nil
+}
+
+//Append write each record in its own object
+func (store *S3HistoryStore) Append(records ...gtdhistory.Record) error {
+	for _, record := range records {
+		content, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("%s%s/%s-%s-%s.json", store.Prefix, record.Env, record.Time.UTC().Format("20060102T150405.000000000Z"), record.Action, record.Service)
+		_, err = store.svc.PutObject(&s3.PutObjectInput{
+			Bucket:      aws.String(store.Bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(content),
+			ContentType: aws.String("application/json"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Query read the records matching filter, oldest first
+func (store *S3HistoryStore) Query(filter gtdhistory.Filter) ([]gtdhistory.Record, error) {
+	prefix := store.Prefix
+	if !strings.EqualFold("", filter.Env) {
+		prefix = fmt.Sprintf("%s%s/", store.Prefix, filter.Env)
+	}
+
+	keys := make([]string, 0)
+	err := store.svc.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(store.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			// skip objects older than filter.Since without downloading them
+			if !filter.Since.IsZero() && object.LastModified != nil && object.LastModified.Before(filter.Since) {
+				continue
+			}
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]gtdhistory.Record, 0, len(keys))
+	for _, key := range keys {
+		result, err := store.svc.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(store.Bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(result.Body)
+		result.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		var record gtdhistory.Record
+		if err := json.Unmarshal(content, &record); err != nil {
+			return nil, fmt.Errorf("invalid history object %s: %v", key, err)
+		}
+		if filter.Match(record) {
+			records = append(records, record)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
@@ -0,0 +1,276 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gpkfr/goretdep/gtdlock"
+)
+
+type (
+	//DynamoDBLockBackend store locks in a DynamoDB table
+	//whose partition key is the string 'lock_key'
+	DynamoDBLockBackend struct {
+		svc   *dynamodb.DynamoDB
+		Table string
+	}
+
+	//S3LockBackend store locks as json objects under Bucket/Prefix.
+	//S3 offers no conditional write here, concurrent acquisitions are best effort.
+	S3LockBackend struct {
+		svc    *s3.S3
+		Bucket string
+		Prefix string
+	}
+)
+
+//NewDynamoDBLockBackend Return a lock backend on table
+func (awsSession *AWSSession) NewDynamoDBLockBackend(table string) *DynamoDBLockBackend {
+	return &DynamoDBLockBackend{
+		svc:   dynamodb.New(awsSession.Client),
+		Table: table,
+	}
+}
+
+//NewS3LockBackend Return a lock backend on bucket
+func (awsSession *AWSSession) NewS3LockBackend(bucket, prefix string) *S3LockBackend {
+	return &S3LockBackend{
+		svc:    s3.New(awsSession.Client),
+		Bucket: bucket,
+		Prefix: prefix,
+	}
+}
+
+//Acquire put the lock item, unless a lock not expired is held
+func (backend *DynamoDBLockBackend) Acquire(lock *gtdlock.Lock) error {
+	_, err := backend.svc.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(backend.Table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"lock_key":   {S: aws.String(lock.Key)},
+			"token":      {S: aws.String(lock.Token)},
+			"owner":      {S: aws.String(lock.Owner)},
+			"reason":     {S: aws.String(lock.Reason)},
+			"command":    {S: aws.String(lock.Command)},
+			"created_at": {N: aws.String(strconv.FormatInt(lock.CreatedAt.Unix(), 10))},
+			"expires_at": {N: aws.String(strconv.FormatInt(lock.ExpiresAt.Unix(), 10))},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(lock_key) OR expires_at < :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))},
+		},
+	})
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		current, getErr := backend.Get(lock.Key)
+		if getErr != nil {
+			return getErr
+		}
+		if current != nil {
+			return &gtdlock.LockedError{Lock: current}
+		}
+	}
+	return err
+}
+
+//Release delete the lock item, when it holds token unless force is set
+func (backend *DynamoDBLockBackend) Release(key, token string, force bool) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(backend.Table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"lock_key": {S: aws.String(key)},
+		},
+	}
+	if !force {
+		input.ConditionExpression = aws.String("attribute_not_exists(lock_key) OR #token = :token")
+		input.ExpressionAttributeNames = map[string]*string{"#token": aws.String("token")}
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":token": {S: aws.String(token)}}
+	}
+
+	_, err := backend.svc.DeleteItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		current, getErr := backend.Get(key)
+		if getErr != nil {
+			return getErr
+		}
+		if current != nil {
+			return &gtdlock.LockedError{Lock: current}
+		}
+		return nil
+	}
+	return err
+}
+
+//Refresh update the expiration of the lock item, when it still holds the token of lock
+func (backend *DynamoDBLockBackend) Refresh(lock *gtdlock.Lock) error {
+	_, err := backend.svc.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(backend.Table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"lock_key": {S: aws.String(lock.Key)},
+		},
+		UpdateExpression:         aws.String("SET expires_at = :expires"),
+		ConditionExpression:      aws.String("#token = :token"),
+		ExpressionAttributeNames: map[string]*string{"#token": aws.String("token")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":token":   {S: aws.String(lock.Token)},
+			":expires": {N: aws.String(strconv.FormatInt(lock.ExpiresAt.Unix(), 10))},
+		},
+	})
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		current, getErr := backend.Get(lock.Key)
+		if getErr != nil {
+			return getErr
+		}
+		if current != nil {
+			return &gtdlock.LockedError{Lock: current}
+		}
+		return fmt.Errorf("lock %s was released", lock.Key)
+	}
+	return err
+}
+
+//Get read the lock item
+func (backend *DynamoDBLockBackend) Get(key string) (*gtdlock.Lock, error) {
+	result, err := backend.svc.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(backend.Table),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"lock_key": {S: aws.String(key)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Item) == 0 {
+		return nil, nil
+	}
+
+	lock := &gtdlock.Lock{Key: key}
+	if v, ok := result.Item["token"]; ok {
+		lock.Token = aws.StringValue(v.S)
+	}
+	if v, ok := result.Item["owner"]; ok {
+		lock.Owner = aws.StringValue(v.S)
+	}
+	if v, ok := result.Item["reason"]; ok {
+		lock.Reason = aws.StringValue(v.S)
+	}
+	if v, ok := result.Item["command"]; ok {
+		lock.Command = aws.StringValue(v.S)
+	}
+	if v, ok := result.Item["created_at"]; ok {
+		if seconds, err := strconv.ParseInt(aws.StringValue(v.N), 10, 64); err == nil {
+			lock.CreatedAt = time.Unix(seconds, 0)
+		}
+	}
+	if v, ok := result.Item["expires_at"]; ok {
+		if seconds, err := strconv.ParseInt(aws.StringValue(v.N), 10, 64); err == nil {
+			lock.ExpiresAt = time.Unix(seconds, 0)
+		}
+	}
+	return lock, nil
+}
+
+func (backend *S3LockBackend) objectKey(key string) string {
+	return fmt.Sprintf("%s%s.json", backend.Prefix, key)
+}
+
+//Acquire write the lock object, unless a lock not expired is held
+func (backend *S3LockBackend) Acquire(lock *gtdlock.Lock) error {
+	current, err := backend.Get(lock.Key)
+	if err != nil {
+		return err
+	}
+	if current != nil && !current.Expired() {
+		return &gtdlock.LockedError{Lock: current}
+	}
+
+	content, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	_, err = backend.svc.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(backend.Bucket),
+		Key:         aws.String(backend.objectKey(lock.Key)),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+//Release delete the lock object, when it holds token unless force is set
+func (backend *S3LockBackend) Release(key, token string, force bool) error {
+	current, err := backend.Get(key)
+	if err != nil || current == nil {
+		return err
+	}
+	if !force && current.Token != token {
+		return &gtdlock.LockedError{Lock: current}
+	}
+
+	_, err = backend.svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(backend.Bucket),
+		Key:    aws.String(backend.objectKey(key)),
+	})
+	return err
+}
+
+//Refresh rewrite the lock object with the new expiration of lock
+func (backend *S3LockBackend) Refresh(lock *gtdlock.Lock) error {
+	current, err := backend.Get(lock.Key)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("lock %s was released", lock.Key)
+	}
+	if current.Token != lock.Token {
+		return &gtdlock.LockedError{Lock: current}
+	}
+
+	content, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	_, err = backend.svc.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(backend.Bucket),
+		Key:         aws.String(backend.objectKey(lock.Key)),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+//Get read the lock object
+func (backend *S3LockBackend) Get(key string) (*gtdlock.Lock, error) {
+	result, err := backend.svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(backend.Bucket),
+		Key:    aws.String(backend.objectKey(key)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer result.Body.Close()
+
+	content, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &gtdlock.Lock{}
+	if err := json.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("invalid lock object %s: %v", backend.objectKey(key), err)
+	}
+	return lock, nil
+}
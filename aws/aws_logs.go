@@ -0,0 +1,116 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+//LogStream is the CloudWatch Logs stream of a container
+type LogStream struct {
+	Group  string
+	Prefix string
+	Stream string
+	Region string
+}
+
+//TaskID Return the id of a task from its ARN
+func TaskID(taskArn string) string {
+	return taskArn[strings.LastIndex(taskArn, "/")+1:]
+}
+
+//ContainerLogStream Return the awslogs stream of container for the task taskID.
+//An empty taskID Return the stream prefix of every task of the container.
+func ContainerLogStream(container *ecs.ContainerDefinition, taskID string) (*LogStream, error) {
+	if container.LogConfiguration == nil || aws.StringValue(container.LogConfiguration.LogDriver) != "awslogs" {
+		return nil, fmt.Errorf("container %s does not use the awslogs log driver", aws.StringValue(container.Name))
+	}
+
+	options := aws.StringValueMap(container.LogConfiguration.Options)
+	if options["awslogs-group"] == "" || options["awslogs-stream-prefix"] == "" {
+		return nil, fmt.Errorf("container %s has no awslogs-group or awslogs-stream-prefix", aws.StringValue(container.Name))
+	}
+
+	// prefix/container-name/task-id
+	prefix := fmt.Sprintf("%s/%s/", options["awslogs-stream-prefix"], aws.StringValue(container.Name))
+	return &LogStream{
+		Group:  options["awslogs-group"],
+		Prefix: prefix,
+		Stream: fmt.Sprintf("%s%s", prefix, taskID),
+		Region: options["awslogs-region"],
+	}, nil
+}
+
+func (awsSession *AWSSession) logsClient(region string) *cloudwatchlogs.CloudWatchLogs {
+	if region == "" {
+		return cloudwatchlogs.New(awsSession.Client)
+	}
+	return cloudwatchlogs.New(awsSession.Client, aws.NewConfig().WithRegion(region))
+}
+
+//GetLogEvents Return the events of stream after token (from the start when token is nil)
+//and the token to get the next ones. A stream not created yet has no event.
+func (awsSession *AWSSession) GetLogEvents(stream *LogStream, token *string) ([]*cloudwatchlogs.OutputLogEvent, *string, error) {
+	input := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(stream.Group),
+		LogStreamName: aws.String(stream.Stream),
+		StartFromHead: aws.Bool(true),
+		NextToken:     token,
+	}
+
+	result, err := awsSession.logsClient(stream.Region).GetLogEvents(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudwatchlogs.ErrCodeResourceNotFoundException {
+			return nil, token, nil
+		}
+		return nil, token, err
+	}
+	return result.Events, result.NextForwardToken, nil
+}
+
+//FilterLogEvents Return the events of streams in group, from start (milliseconds since epoch),
+//matching pattern when not empty, ordered by timestamp.
+//Every stream of the group with prefix is read when streams is empty, too long for the API
+//or not created yet.
+func (awsSession *AWSSession) FilterLogEvents(group, region, prefix string, streams []string, start int64, pattern string) ([]*cloudwatchlogs.FilteredLogEvent, error) {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(group),
+		StartTime:    aws.Int64(start),
+	}
+	// FilterLogEvents accepts 100 streams at most
+	if len(streams) == 0 || len(streams) > 100 {
+		input.LogStreamNamePrefix = aws.String(prefix)
+	} else {
+		input.LogStreamNames = aws.StringSlice(streams)
+	}
+	if pattern != "" {
+		input.FilterPattern = aws.String(pattern)
+	}
+
+	events := make([]*cloudwatchlogs.FilteredLogEvent, 0)
+	collect := func(page *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+		events = append(events, page.Events...)
+		return !lastPage
+	}
+	err := awsSession.logsClient(region).FilterLogEventsPages(input, collect)
+	// the stream of a task just started may not exist yet
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudwatchlogs.ErrCodeResourceNotFoundException && input.LogStreamNames != nil {
+		input.LogStreamNames = nil
+		input.LogStreamNamePrefix = aws.String(prefix)
+		events = events[:0]
+		err = awsSession.logsClient(region).FilterLogEventsPages(input, collect)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return aws.Int64Value(events[i].Timestamp) < aws.Int64Value(events[j].Timestamp)
+	})
+	return events, nil
+}
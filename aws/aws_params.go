@@ -0,0 +1,244 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+const (
+	//ParamStoreSSM is the SSM Parameter Store
+	ParamStoreSSM = "ssm"
+	//ParamStoreSecretsManager is AWS Secrets Manager
+	ParamStoreSecretsManager = "secretsmanager"
+)
+
+//Param is a parameter (or secret) referenced by task definitions
+type Param struct {
+	Name  string
+	Store string
+	ARN   string
+	Value string
+	// version (SSM) or version id (Secrets Manager)
+	Version string
+}
+
+//ListParams Return the SSM parameters and the Secrets Manager secrets under path.
+//Values are only read when withValues.
+func (awsSession *AWSSession) ListParams(path string, withValues bool) ([]*Param, error) {
+	params := make([]*Param, 0)
+
+	ssmSvc := ssm.New(awsSession.Client)
+	err := ssmSvc.GetParametersByPathPages(&ssm.GetParametersByPathInput{
+		Path:           aws.String(strings.TrimSuffix(path, "/")),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(withValues),
+	}, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, parameter := range page.Parameters {
+			param := &Param{
+				Name:    aws.StringValue(parameter.Name),
+				Store:   ParamStoreSSM,
+				ARN:     aws.StringValue(parameter.ARN),
+				Version: fmt.Sprintf("%d", aws.Int64Value(parameter.Version)),
+			}
+			if withValues {
+				param.Value = aws.StringValue(parameter.Value)
+			}
+			params = append(params, param)
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	smSvc := secretsmanager.New(awsSession.Client)
+	secretPrefix := strings.TrimPrefix(path, "/")
+	err = smSvc.ListSecretsPages(&secretsmanager.ListSecretsInput{
+		Filters: []*secretsmanager.Filter{{
+			Key:    aws.String(secretsmanager.FilterNameStringTypeName),
+			Values: aws.StringSlice([]string{secretPrefix}),
+		}},
+	}, func(page *secretsmanager.ListSecretsOutput, lastPage bool) bool {
+		for _, secret := range page.SecretList {
+			// the name filter is a prefix on words, keep the exact path only
+			if !strings.HasPrefix(aws.StringValue(secret.Name), secretPrefix) {
+				continue
+			}
+			params = append(params, &Param{
+				Name:  aws.StringValue(secret.Name),
+				Store: ParamStoreSecretsManager,
+				ARN:   aws.StringValue(secret.ARN),
+			})
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if withValues {
+		for _, param := range params {
+			if param.Store != ParamStoreSecretsManager {
+				continue
+			}
+			secret, err := awsSession.GetParam(ParamStoreSecretsManager, param.Name)
+			if err != nil {
+				return nil, err
+			}
+			param.Value = secret.Value
+			param.Version = secret.Version
+		}
+	}
+
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Name < params[j].Name
+	})
+	return params, nil
+}
+
+//GetParam Return a parameter with its decrypted value
+func (awsSession *AWSSession) GetParam(store, name string) (*Param, error) {
+	if store == ParamStoreSecretsManager {
+		result, err := secretsmanager.New(awsSession.Client).GetSecretValue(&secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(name),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &Param{
+			Name:    aws.StringValue(result.Name),
+			Store:   store,
+			ARN:     aws.StringValue(result.ARN),
+			Value:   aws.StringValue(result.SecretString),
+			Version: aws.StringValue(result.VersionId),
+		}, nil
+	}
+
+	result, err := ssm.New(awsSession.Client).GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Param{
+		Name:    aws.StringValue(result.Parameter.Name),
+		Store:   ParamStoreSSM,
+		ARN:     aws.StringValue(result.Parameter.ARN),
+		Value:   aws.StringValue(result.Parameter.Value),
+		Version: fmt.Sprintf("%d", aws.Int64Value(result.Parameter.Version)),
+	}, nil
+}
+
+//PutParam create or update a parameter (SSM SecureString) or a secret,
+//and Return its ARN
+func (awsSession *AWSSession) PutParam(store, name, value string) (string, error) {
+	if store == ParamStoreSecretsManager {
+		svc := secretsmanager.New(awsSession.Client)
+		result, err := svc.PutSecretValue(&secretsmanager.PutSecretValueInput{
+			SecretId:     aws.String(name),
+			SecretString: aws.String(value),
+		})
+		if err == nil {
+			return aws.StringValue(result.ARN), nil
+		}
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != secretsmanager.ErrCodeResourceNotFoundException {
+			return "", err
+		}
+
+		created, err := svc.CreateSecret(&secretsmanager.CreateSecretInput{
+			Name:         aws.String(name),
+			SecretString: aws.String(value),
+		})
+		if err != nil {
+			return "", err
+		}
+		return aws.StringValue(created.ARN), nil
+	}
+
+	svc := ssm.New(awsSession.Client)
+	_, err := svc.PutParameter(&ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Value:     aws.String(value),
+		Type:      aws.String(ssm.ParameterTypeSecureString),
+		Overwrite: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	param, err := awsSession.GetParam(ParamStoreSSM, name)
+	if err != nil {
+		return "", err
+	}
+	return param.ARN, nil
+}
+
+//DeleteParam delete a parameter or a secret (without recovery window)
+func (awsSession *AWSSession) DeleteParam(store, name string) error {
+	if store == ParamStoreSecretsManager {
+		_, err := secretsmanager.New(awsSession.Client).DeleteSecret(&secretsmanager.DeleteSecretInput{
+			SecretId:                   aws.String(name),
+			ForceDeleteWithoutRecovery: aws.Bool(true),
+		})
+		return err
+	}
+
+	_, err := ssm.New(awsSession.Client).DeleteParameter(&ssm.DeleteParameterInput{
+		Name: aws.String(name),
+	})
+	return err
+}
+
+//ParamExists tell if the secret referenced by a task definition exists.
+//valueFrom is a SSM parameter name or ARN, or a Secrets Manager ARN
+//(optionally followed by :json-key:version-stage:version-id).
+func (awsSession *AWSSession) ParamExists(valueFrom string) (bool, error) {
+	config := aws.NewConfig()
+	parts := strings.Split(valueFrom, ":")
+	if len(parts) >= 6 && parts[0] == "arn" {
+		config = config.WithRegion(parts[3])
+	}
+
+	if strings.HasPrefix(valueFrom, "arn:") && len(parts) >= 7 && parts[2] == ParamStoreSecretsManager {
+		// arn:aws:secretsmanager:region:account:secret:name-suffix
+		_, err := secretsmanager.New(awsSession.Client, config).DescribeSecret(&secretsmanager.DescribeSecretInput{
+			SecretId: aws.String(strings.Join(parts[:7], ":")),
+		})
+		return paramFound(err)
+	}
+
+	name := valueFrom
+	if strings.HasPrefix(valueFrom, "arn:") {
+		// arn:aws:ssm:region:account:parameter/name
+		i := strings.Index(valueFrom, ":parameter")
+		if i < 0 {
+			return false, fmt.Errorf("unsupported secret reference %s", valueFrom)
+		}
+		name = strings.TrimPrefix(valueFrom[i:], ":parameter")
+	}
+
+	_, err := ssm.New(awsSession.Client, config).GetParameter(&ssm.GetParameterInput{
+		Name: aws.String(name),
+	})
+	return paramFound(err)
+}
+
+func paramFound(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case ssm.ErrCodeParameterNotFound, secretsmanager.ErrCodeResourceNotFoundException:
+			return false, nil
+		}
+	}
+	return false, err
+}
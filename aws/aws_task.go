@@ -0,0 +1,128 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+//RunServiceTask start a one-off task of taskDefinition with the network configuration,
+//launch type or capacity providers and platform version of service
+func (awsSession *AWSSession) RunServiceTask(svc *ecs.ECS, service *ecs.Service, cluster, taskDefinition string, overrides *ecs.TaskOverride) (*ecs.Task, error) {
+	input := &ecs.RunTaskInput{
+		Cluster:              aws.String(cluster),
+		TaskDefinition:       aws.String(taskDefinition),
+		Count:                aws.Int64(1),
+		StartedBy:            aws.String("gtd"),
+		NetworkConfiguration: service.NetworkConfiguration,
+		PlatformVersion:      service.PlatformVersion,
+		Overrides:            overrides,
+	}
+	if len(service.CapacityProviderStrategy) > 0 {
+		input.CapacityProviderStrategy = service.CapacityProviderStrategy
+	} else {
+		input.LaunchType = service.LaunchType
+	}
+
+	result, err := awsMust(svc.RunTask(input))
+	if err != nil {
+		return nil, err
+	}
+
+	output := result.(*ecs.RunTaskOutput)
+	if len(output.Failures) > 0 {
+		failures := make([]string, 0, len(output.Failures))
+		for _, failure := range output.Failures {
+			failures = append(failures, fmt.Sprintf("%s %s", aws.StringValue(failure.Reason), aws.StringValue(failure.Detail)))
+		}
+		return nil, fmt.Errorf("run task failed: %s", strings.Join(failures, ", "))
+	}
+	if len(output.Tasks) == 0 {
+		return nil, fmt.Errorf("run task failed: no task started")
+	}
+	return output.Tasks[0], nil
+}
+
+//DescribeTask Return the ECS description of a single task
+func (awsSession *AWSSession) DescribeTask(svc *ecs.ECS, cluster, taskArn string) (*ecs.Task, error) {
+	result, err := awsMust(svc.DescribeTasks(&ecs.DescribeTasksInput{
+		Cluster: aws.String(cluster),
+		Tasks:   aws.StringSlice([]string{taskArn}),
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	if tasks := result.(*ecs.DescribeTasksOutput).Tasks; len(tasks) > 0 {
+		return tasks[0], nil
+	}
+	return nil, fmt.Errorf("task %s not found on cluster %s", taskArn, cluster)
+}
+
+//StopTask stop a running task, reason is shown in the ECS console
+func (awsSession *AWSSession) StopTask(svc *ecs.ECS, cluster, taskArn, reason string) error {
+	_, err := awsMust(svc.StopTask(&ecs.StopTaskInput{
+		Cluster: aws.String(cluster),
+		Task:    aws.String(taskArn),
+		Reason:  aws.String(reason),
+	}))
+	return err
+}
+
+//CountFailedTasks Return the number of stopped tasks started by deployment
+//(an ECS deployment id) that failed to start or whose essential container exited.
+//Tasks stopped by the scheduler (scale in, replaced) are not counted.
+func (awsSession *AWSSession) CountFailedTasks(svc *ecs.ECS, cluster, deployment string) (int64, error) {
+	taskArns := make([]string, 0)
+	err := svc.ListTasksPages(&ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		StartedBy:     aws.String(deployment),
+		DesiredStatus: aws.String(ecs.DesiredStatusStopped),
+	}, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+		taskArns = append(taskArns, aws.StringValueSlice(page.TaskArns)...)
+		return !lastPage
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var failed int64
+	// DescribeTasks takes up to 100 tasks
+	for len(taskArns) > 0 {
+		n := len(taskArns)
+		if n > 100 {
+			n = 100
+		}
+		result, err := awsMust(svc.DescribeTasks(&ecs.DescribeTasksInput{
+			Cluster: aws.String(cluster),
+			Tasks:   aws.StringSlice(taskArns[:n]),
+		}))
+		if err != nil {
+			return 0, err
+		}
+		for _, task := range result.(*ecs.DescribeTasksOutput).Tasks {
+			switch aws.StringValue(task.StopCode) {
+			case ecs.TaskStopCodeTaskFailedToStart, ecs.TaskStopCodeEssentialContainerExited:
+				failed++
+			}
+		}
+		taskArns = taskArns[n:]
+	}
+	return failed, nil
+}
+
+//ListServiceTasks Return the ARNs of the running tasks of service
+func (awsSession *AWSSession) ListServiceTasks(svc *ecs.ECS, cluster, service string) ([]string, error) {
+	taskArns := make([]string, 0)
+	err := svc.ListTasksPages(&ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		ServiceName:   aws.String(service),
+		DesiredStatus: aws.String(ecs.DesiredStatusRunning),
+	}, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+		taskArns = append(taskArns, aws.StringValueSlice(page.TaskArns)...)
+		return !lastPage
+	})
+	return taskArns, err
+}
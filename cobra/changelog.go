@@ -0,0 +1,116 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	gtdaws "github.com/gpkfr/goretdep/aws"
+	"github.com/gpkfr/goretdep/config"
+	"github.com/gpkfr/goretdep/gtdgit"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+var showChangelog bool
+
+//NewChangelogCommand bind the changelog command
+func NewChangelogCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "changelog",
+		Short: "List the commits between the deployed and the target images",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			changelog(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+
+	cobraCmd.Flags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
+	cobraCmd.Flags().StringSliceVarP(&cmd.SelectedServices, "service", "s", []string{}, "Service(s) to show. Separated by comma")
+	cobraCmd.Flags().StringVarP(&newContainerImage, "container-image", "c", "", "Container Image to deploy")
+	cobraCmd.Flags().StringVarP(&newContainerTag, "tag", "t", "", "tag of Image to deploy")
+	cobraCmd.Flags().StringSliceVar(&deployContainers, "containers", []string{}, "Container(s) of the task definition to compare. Separated by comma")
+
+	cmd.AddCommand(cobraCmd)
+}
+
+func changelog(cmd *Command) {
+	if strings.EqualFold("", newContainerImage) && strings.EqualFold("", newContainerTag) {
+		log.Fatal("changelog needs the target image, use --tag or --container-image")
+	}
+
+	cmd.AWSSession.GetServices(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, cmd.GTenv, false, cmd.SelectedServices...)
+
+	for i := range cmd.Services.Services {
+		if cmd.Services.Services[i].TaskDefinition != nil {
+			printChangelog(cmd, &cmd.Services.Services[i])
+		}
+	}
+}
+
+//printChangelog list the commits between the image of aService and the one a deploy would use.
+//Commits are read from the service's source_path checkout, the compare URL is built from its github field.
+func printChangelog(cmd *Command, aService *config.Service) {
+	containers, err := gtdaws.SelectContainerDefinitions(aService.TaskDefinition.ContainerDefinitions, serviceContainers(aService)...)
+	if err != nil {
+		fmt.Printf("%s: %v\n\n", aService.Name, err)
+		return
+	}
+
+	currentImage := aws.StringValue(containers[0].Image)
+	targetImage, err := desiredImage(aService, currentImage)
+	if err != nil {
+		fmt.Printf("%s: %v\n\n", aService.Name, err)
+		return
+	}
+
+	from := gtdgit.CommitFromImage(currentImage)
+	to := gtdgit.CommitFromImage(targetImage)
+	fmt.Printf("%s: %s -> %s\n", aService.Name, currentImage, targetImage)
+
+	switch {
+	case strings.EqualFold("", from) || strings.EqualFold("", to):
+		fmt.Printf("    no commit found in the image tags\n\n")
+		return
+	case strings.EqualFold(from, to):
+		fmt.Printf("    same commit %s\n\n", from)
+		return
+	}
+
+	github := aService.Github
+	if strings.EqualFold("", github) {
+		github = cmd.Services.Github
+	}
+	if compareURL := gtdgit.CompareURL(github, from, to); !strings.EqualFold("", compareURL) {
+		fmt.Printf("    %s\n", compareURL)
+	}
+
+	if strings.EqualFold("", aService.SourcePath) {
+		fmt.Printf("    %s..%s (set source_path to list the commits)\n\n", from, to)
+		return
+	}
+
+	path, err := homedir.Expand(aService.SourcePath)
+	if err != nil {
+		path = aService.SourcePath
+	}
+	commits, err := gtdgit.Log(path, from, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "    %v\n\n", err)
+		return
+	}
+
+	if len(commits) == 0 {
+		fmt.Printf("    no commit between %s and %s (rollback to an older commit?)\n", from, to)
+	}
+	for _, commit := range commits {
+		fmt.Printf("    %s %s %-20s %s\n", commit.SHA, commit.Date, commit.Author, commit.Subject)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,94 @@
+package cobra
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/gpkfr/goretdep/config"
+	"github.com/gpkfr/goretdep/gtddocker"
+)
+
+//deployContainers override the containers named in the stack file
+var deployContainers []string
+
+//serviceContainers Return the names of the containers to update on aService,
+//an empty list means the first container of the task definition.
+func serviceContainers(aService *config.Service) []string {
+	if len(deployContainers) > 0 {
+		return deployContainers
+	}
+	if !strings.EqualFold("", aService.Container) {
+		return []string{aService.Container}
+	}
+	return nil
+}
+
+//childTaskContainers Return the names of the containers to update on a child task
+func childTaskContainers(childTask config.ChildTask) []string {
+	if len(deployContainers) > 0 {
+		return deployContainers
+	}
+	if !strings.EqualFold("", childTask.Container) {
+		return []string{childTask.Container}
+	}
+	return nil
+}
+
+//targetImages Return the image to deploy on each container (by name).
+//A single container gets image, several containers keep their own repository
+//and only get the tag of image. With keep, every container keeps its current image.
+func targetImages(containers []*ecs.ContainerDefinition, image string, keep bool) (map[string]string, error) {
+	images := make(map[string]string, len(containers))
+	if len(containers) == 1 && !keep {
+		images[aws.StringValue(containers[0].Name)] = image
+		return images, nil
+	}
+	if !keep && !strings.EqualFold("", newContainerImage) {
+		return nil, fmt.Errorf("--container-image can only be used with a single container, use --tag")
+	}
+
+	tag := strings.TrimPrefix(gtddocker.Tagged(image), gtddocker.Untagged(image))
+	for _, container := range containers {
+		current := aws.StringValue(container.Image)
+		if keep {
+			images[aws.StringValue(container.Name)] = current
+			continue
+		}
+		retagged := fmt.Sprintf("%s%s", gtddocker.Untagged(current), tag)
+		if pinned, ok := pinnedImages[retagged]; ok {
+			retagged = pinned
+		}
+		images[aws.StringValue(container.Name)] = retagged
+	}
+	return images, nil
+}
+
+//keepCurrentImages tell if a forced deploy redeploys the current images of aService
+func keepCurrentImages(aService *config.Service) bool {
+	return forceDeploy && strings.EqualFold("", newContainerTag) && strings.EqualFold("", newContainerImage) && strings.EqualFold("", aService.DesiredImage)
+}
+
+//containerNames Return the names of containers
+func containerNames(containers []*ecs.ContainerDefinition) []string {
+	names := make([]string, 0, len(containers))
+	for _, container := range containers {
+		names = append(names, aws.StringValue(container.Name))
+	}
+	return names
+}
+
+//containerImages format the images of containers, one per line.
+//Images are prefixed by their container name when there is more than one.
+func containerImages(containers []*ecs.ContainerDefinition) string {
+	if len(containers) == 1 {
+		return aws.StringValue(containers[0].Image)
+	}
+
+	images := make([]string, 0, len(containers))
+	for _, container := range containers {
+		images = append(images, fmt.Sprintf("%s: %s", aws.StringValue(container.Name), aws.StringValue(container.Image)))
+	}
+	return strings.Join(images, "\n")
+}
@@ -4,15 +4,19 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"reflect"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	gtdaws "github.com/gpkfr/goretdep/aws"
 	"github.com/gpkfr/goretdep/config"
 	"github.com/gpkfr/goretdep/gtddocker"
+	"github.com/gpkfr/goretdep/gtdhistory"
+	"github.com/gpkfr/goretdep/gtdnotify"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/mitchellh/go-homedir"
 
 	"github.com/spf13/cobra"
 )
@@ -22,8 +26,54 @@ var (
 	newContainerTag     string
 	forceDeploy         bool
 	environmentFilePath string
+	waitDeploy          bool
+	waitTimeout         time.Duration
+	autoRollback        bool
+	planDeploy          bool
+	deployParallel      int
+	canaryService       string
+	canaryBake          time.Duration
+	skipImageCheck      bool
+	pinDigest           bool
+	assumeYes           bool
 )
 
+//serviceResult hold the outcome of a service deploy
+type serviceResult struct {
+	service *config.Service
+	// the service's row comes first, then its ECR and child tasks rows
+	rows deployRows
+	// revision the service was updated to, empty when not updated
+	taskDefinition   string
+	previousRevision string
+	children         []childRegistration
+	// audit record, nil when the service was not updated
+	history *gtdhistory.Record
+	err     error
+}
+
+//dockerOutput serialize the docker pulls and pushes of services deployed in parallel
+var dockerOutput sync.Mutex
+
+//childRegistration keep track of a child task revision
+//registered while deploying its parent service (row is relative to the service's rows)
+type childRegistration struct {
+	row            int
+	taskDefinition string
+}
+
+//deployRows buffer the rows of the deploy table,
+//services state can so be refreshed once deployments are over.
+type deployRows struct {
+	rows []table.Row
+}
+
+//AppendRow add a row and return its index
+func (d *deployRows) AppendRow(row table.Row) int {
+	d.rows = append(d.rows, row)
+	return len(d.rows) - 1
+}
+
 func NewDeployCommand(cmd *Command) {
 	cobraCmd := &cobra.Command{
 		Use:   "deploy",
@@ -44,14 +94,24 @@ func NewDeployCommand(cmd *Command) {
 	cobraCmd.Flags().StringVarP(&newContainerTag, "tag", "t", "", "tag of Image to deploy")
 	cobraCmd.Flags().BoolVar(&forceDeploy, "force", false, "Force new deployement")
 	cobraCmd.Flags().StringVar(&environmentFilePath, "config", "", "Task's Config file (Environment)")
+	cobraCmd.Flags().BoolVar(&waitDeploy, "wait", false, "Wait for updated services to reach a steady state")
+	cobraCmd.Flags().DurationVar(&waitTimeout, "timeout", 15*time.Minute, "Maximum time to wait for services to stabilize [--timeout 15m]")
+	cobraCmd.Flags().StringSliceVar(&deployContainers, "containers", []string{}, "Container(s) of the task definition to update. Separated by comma (default: stack file 'container' or first container)")
+	cobraCmd.Flags().IntVar(&deployParallel, "parallel", 1, "Number of services deployed at once")
+	cobraCmd.Flags().StringVar(&canaryService, "canary", "", "Service deployed (and stable) before the others (implies --wait)")
+	cobraCmd.Flags().DurationVar(&canaryBake, "bake", 0, "Time the canary must stay stable before deploying the others [--bake 5m]")
+	cobraCmd.Flags().StringVar(&releaseReason, "reason", "", "Reason of the deploy, shown to whoever finds the environment locked")
+	cobraCmd.Flags().BoolVar(&planDeploy, "plan", false, "Show the changes the deploy would make, without applying them")
+	cobraCmd.Flags().BoolVar(&autoRollback, "auto-rollback", false, "Rollback services that fail to stabilize to their previous revision (implies --wait)")
+	cobraCmd.Flags().BoolVar(&pinDigest, "pin-digest", false, "Deploy the image by digest (repo:tag@sha256:...) so its tag cannot change under the service")
+	cobraCmd.Flags().BoolVar(&assumeYes, "yes", false, "Deploy without confirmation when environment variables are removed")
+	cobraCmd.Flags().BoolVar(&showChangelog, "changelog", false, "List the commits between the deployed and the new images before deploying")
+	cobraCmd.Flags().BoolVar(&skipImageCheck, "skip-image-check", false, "Do not check that the image exists in its registry before deploying")
 
 	cmd.AddCommand(cobraCmd)
 }
 
 func deployServices(cmd *Command) {
-	var currentImage string
-	var newServiceTaskDefinition string = "Unmodified"
-
 	// just do a deploy without image replacement
 	if strings.EqualFold(newContainerImage, newContainerTag) && !forceDeploy {
 		fmt.Println("(💣) Not sure you want to do this. Confirm with `--force`")
@@ -61,168 +121,517 @@ func deployServices(cmd *Command) {
 
 	cmd.AWSSession.GetServices(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, cmd.GTenv, true, cmd.SelectedServices...)
 
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Service", "Current Revision", "New Revision", "Current Image", "Desired Image", "status", "Running count"})
+	if showChangelog {
+		for i := range cmd.Services.Services {
+			if cmd.Services.Services[i].TaskDefinition != nil {
+				printChangelog(cmd, &cmd.Services.Services[i])
+			}
+		}
+	}
 
-	for _, aService := range cmd.Services.Services {
-		if aService.TaskDefinition != nil {
+	if planDeploy {
+		planServices(cmd)
+		return
+	}
 
-			//very Specific to Deploy
-			// Check if we use the current image definition
-			if newContainerImage == "" {
-				newContainerImage = aService.Registry
-				if newContainerTag == "" && forceDeploy {
-					newContainerImage = *aService.TaskDefinition.ContainerDefinitions[0].Image
-				}
+	applyDeploy(cmd)
+}
+
+//applyDeploy deploy the services loaded in cmd,
+//wave by wave, then report, record and notify the results
+func applyDeploy(cmd *Command) {
+	services := make([]*config.Service, 0, len(cmd.Services.Services))
+	for i := range cmd.Services.Services {
+		if cmd.Services.Services[i].TaskDefinition != nil {
+			services = append(services, &cmd.Services.Services[i])
+		}
+	}
+
+	pinDigest = pinDigest || cmd.Services.PinDigest
+	if !skipImageCheck || pinDigest {
+		verifyImages(cmd, services, pinDigest)
+	}
+
+	// images are pinned by now, the deployments use them
+	planned := planDeployments(services)
+
+	verifySecrets(cmd, services, planned)
+
+	if !confirmEnvironmentChanges(services, planned) {
+		fmt.Println("Quitting Now, bye (🐷)")
+		os.Exit(0)
+	}
+
+	// rollback needs to know if the deployment succeeded
+	if autoRollback || cmd.Services.AutoRollback {
+		autoRollback = true
+		waitDeploy = true
+	}
+
+	waves, err := cmd.Services.DeployWaves()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stages := make([][]*config.Service, 0, len(waves))
+	for _, wave := range waves {
+		stage := make([]*config.Service, 0, len(wave))
+		for _, i := range wave {
+			if cmd.Services.Services[i].TaskDefinition != nil {
+				stage = append(stage, &cmd.Services.Services[i])
 			}
+		}
+		if len(stage) > 0 {
+			stages = append(stages, stage)
+		}
+	}
 
-			if newContainerTag != "" {
-				if strings.Contains(newContainerImage, ":") {
-					log.Fatal(fmt.Errorf("Tags already defined in %s\n", newContainerImage))
-				}
-				if !strings.Contains(newContainerTag, ":") {
-					//Add missing colon
-					newContainerTag = fmt.Sprintf(":%s", newContainerTag)
-				}
+	// the canary goes alone, before the rest of its wave
+	canaryStage := -1
+	if !strings.EqualFold("", canaryService) {
+		stages, canaryStage, err = withCanaryStage(stages, canaryService)
+		if err != nil {
+			log.Fatal(err)
+		}
+		waitDeploy = true
+	}
+
+	// a wave starts once the previous one is stable
+	if len(stages) > 1 {
+		waitDeploy = true
+	}
+
+	// post_deploy hooks run once the new tasks are stable
+	for _, stage := range stages {
+		for _, aService := range stage {
+			if len(aService.Hooks.PostDeploy) > 0 {
+				waitDeploy = true
 			}
+		}
+	}
 
-			//update tasks
-			if forceDeploy || !strings.EqualFold(*aService.TaskDefinition.ContainerDefinitions[0].Image, fmt.Sprintf("%s%s", newContainerImage, newContainerTag)) {
-				currentImage = *aService.TaskDefinition.ContainerDefinitions[0].Image
-				var isEnvFile bool = false
-				//Need to read the environment File
+	// nothing may exit without releasing the lock from here
+	release := cmd.AcquireLock("deploy")
+	defer release()
+
+	pending := make([]gtdnotify.Change, 0, len(cmd.Services.Services))
+	for _, stage := range stages {
+		for _, aService := range stage {
+			pending = append(pending, gtdnotify.Change{
+				Service:           aService.Name,
+				OldTaskDefinition: fmt.Sprintf("%s:%d", *aService.TaskDefinition.Family, *aService.TaskDefinition.Revision),
+				OldImage:          containerImages(aService.TaskDefinition.ContainerDefinitions),
+				Status:            "PENDING",
+			})
+		}
+	}
+	cmd.Notify("deploy", gtdnotify.EventStart, pending)
+
+	results := make([]*serviceResult, 0, len(cmd.Services.Services))
+	var stageFailed bool
+	skippedStatus := "SKIPPED (previous wave failed)"
+	for i, stage := range stages {
+		if stageFailed {
+			for _, aService := range stage {
+				results = append(results, skippedResult(aService, skippedStatus))
+			}
+			continue
+		}
 
-				input := &ecs.RegisterTaskDefinitionInput{
-					ContainerDefinitions: aService.TaskDefinition.ContainerDefinitions,
-					Family:               aService.TaskDefinition.Family,
-					// TaskRoleArn:          aService.TaskDefinition.TaskRoleArn,
-				}
+		isCanary := i == canaryStage
+		if isCanary {
+			fmt.Printf("Canary: %s\n", stage[0].Name)
+		} else if len(stages) > 1 {
+			fmt.Printf("Wave %d/%d: %s\n", i+1, len(stages), strings.Join(serviceNames(stage), ", "))
+		}
 
-				if aService.TaskDefinition.TaskRoleArn != nil {
-					if !strings.EqualFold(aService.TaskRoleArn, *aService.TaskDefinition.TaskRoleArn) {
-						input.SetTaskRoleArn(aService.TaskRoleArn)
-						isEnvFile = true
-					}
-				} else {
-					if !strings.EqualFold("", aService.TaskRoleArn) {
-						input.SetTaskRoleArn(aService.TaskRoleArn)
-						isEnvFile = true
-					}
-				}
+		stageResults := deployStage(cmd, stage, planned)
+		if waitDeploy {
+			waitResults(cmd, stageResults)
+		}
 
-				if aService.TaskDefinition.ExecutionRoleArn != nil {
-					// si les valeurs sont différrente / Mis à jour à partir du fichier de config
-					if !strings.EqualFold(aService.TaskExecutionRoleArn, *aService.TaskDefinition.ExecutionRoleArn) {
-						input.SetExecutionRoleArn(aService.TaskExecutionRoleArn)
-						isEnvFile = true
-					} else {
-						input.SetExecutionRoleArn(aService.TaskExecutionRoleArn)
-					}
+		if isCanary {
+			skippedStatus = "SKIPPED (canary failed)"
+			if stageResults[0].err == nil && canaryBake > 0 {
+				bakeCanary(cmd, stageResults[0])
+			}
+		}
 
-				} else {
-					if !strings.EqualFold("", aService.TaskExecutionRoleArn) {
-						input.SetExecutionRoleArn(aService.TaskExecutionRoleArn)
-						isEnvFile = true
-					}
-				}
+		// recorded as soon as their status is final, nothing is lost if gtd stops later
+		recordDeploy(cmd, stageResults)
 
-				if !strings.EqualFold("", environmentFilePath) {
-					log.Printf("Read Env File %s", environmentFilePath)
-					taskEnv, err := config.ReadTaskEnvFile(environmentFilePath)
-					if err != nil {
-						log.Fatal(fmt.Errorf("Error while Reading %s", environmentFilePath))
-					}
-					isEnvFile = true
-
-					tasksEnv := make([]*ecs.KeyValuePair, 0)
-					secretsEnv := make([]*ecs.Secret, 0)
-					for k, v := range taskEnv {
-						if strings.HasPrefix(k, "_") {
-							k := strings.TrimPrefix(k, "_")
-							secretsEnv = append(secretsEnv, &ecs.Secret{
-								Name:      aws.String(k),
-								ValueFrom: aws.String(v),
-							})
-						} else {
-							tasksEnv = append(tasksEnv, &ecs.KeyValuePair{
-								Name:  aws.String(k),
-								Value: aws.String(v),
-							})
-						}
-					}
-					input.ContainerDefinitions[0].SetEnvironment(tasksEnv)
-					aService.TasksEnv = tasksEnv
+		for _, result := range stageResults {
+			if result.err != nil {
+				stageFailed = true
+			}
+		}
+		results = append(results, stageResults...)
+	}
 
-					input.ContainerDefinitions[0].SetSecrets(secretsEnv)
-					aService.SecretsEnv = secretsEnv
-					if len(secretsEnv) > 0 {
-						input.SetExecutionRoleArn(aService.TaskExecutionRoleArn)
-					}
-				}
+	renderDeployTable(cmd, results)
 
-				labels := make(map[string]*string)
+	var failed bool
+	for _, result := range results {
+		if result.err != nil {
+			log.Printf("%s: %v", result.service.Name, result.err)
+			failed = true
+		}
+	}
 
-				if len(aService.Labels) > 0 {
-					for _, label := range aService.Labels {
-						value := label.Value
-						labels[label.Key] = &value
-					}
-				}
+	if failed {
+		cmd.Notify("deploy", gtdnotify.EventFailure, deployChanges(results))
+	} else {
+		cmd.Notify("deploy", gtdnotify.EventSuccess, deployChanges(results))
+	}
+	if failed {
+		release()
+		os.Exit(1)
+	}
+}
 
-				if eq := reflect.DeepEqual(input.ContainerDefinitions[0].DockerLabels, labels); !eq {
-					input.ContainerDefinitions[0].SetDockerLabels(labels)
-					isEnvFile = true
-				}
+//deployStage deploy services, up to deployParallel at once.
+//Results are returned in the order of services.
+func deployStage(cmd *Command, services []*config.Service, planned deployments) []*serviceResult {
+	results := make([]*serviceResult, len(services))
 
-				if isEnvFile || !strings.EqualFold(*aService.TaskDefinition.ContainerDefinitions[0].Image, fmt.Sprintf("%s%s", newContainerImage, newContainerTag)) {
-					input.ContainerDefinitions[0].SetImage(fmt.Sprintf("%s%s", newContainerImage, newContainerTag))
+	parallel := deployParallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	semaphore := make(chan struct{}, parallel)
+
+	var wg sync.WaitGroup
+	for i, aService := range services {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, aService *config.Service) {
+			defer wg.Done()
+			results[i] = deployService(cmd, aService, planned[aService])
+			<-semaphore
+		}(i, aService)
+	}
+	wg.Wait()
 
-					result, err := cmd.AWSSession.Svc.RegisterTaskDefinition(input)
-					if err != nil {
-						log.Fatal(fmt.Errorf("error while registering task definifition : %s\n%s", *aService.TaskDefinition.Family, err.Error()))
-					}
-					newServiceTaskDefinition = fmt.Sprintf("%s:%d", *result.TaskDefinition.Family, *result.TaskDefinition.Revision)
-				} else {
-					newServiceTaskDefinition = fmt.Sprintf("%s:%d", *aService.TaskDefinition.Family, *aService.TaskDefinition.Revision)
-				}
+	return results
+}
 
-				//Update Service
-				_, err := cmd.AWSSession.UpdateAWSService(cmd.AWSSession.Svc, &aService.Name, &cmd.Services.ECSCluster, &newServiceTaskDefinition, forceDeploy)
-				if err != nil {
-					log.Println(fmt.Errorf("error while updating service: %s\n %s", aService.Name, err.Error()))
-				}
+//deployService register the new revision of aService planned if needed,
+//update the service, then publish its image and child tasks.
+func deployService(cmd *Command, aService *config.Service, planned *plannedDeployment) *serviceResult {
+	result := &serviceResult{service: aService}
+	currentRevision := fmt.Sprintf("%s:%d", *aService.TaskDefinition.Family, *aService.TaskDefinition.Revision)
+
+	deployment, err := planned.deployment, planned.err
+	if err != nil {
+		result.err = err
+		result.rows.AppendRow([]interface{}{
+			aService.Name,
+			currentRevision,
+			"Unmodified",
+			containerImages(aService.TaskDefinition.ContainerDefinitions),
+			"-",
+			"FAILED",
+			aService.RunningCount})
+		return result
+	}
 
-				t.AppendRow([]interface{}{
-					aService.Name,
-					fmt.Sprintf("%s:%d", *aService.TaskDefinition.Family, *aService.TaskDefinition.Revision),
-					newServiceTaskDefinition,
-					currentImage,
-					fmt.Sprintf("%s%s", newContainerImage, newContainerTag),
-					aService.Status,
-					aService.RunningCount})
-
-				//Ok we have updated service
-				//But do we need to publish a ECR, or push Image with another name ?
-				if !strings.EqualFold("", aService.UpdateECR) {
-					publishRegistry(cmd, t, &aService)
-				}
+	if !deployment.update {
+		// Skipping Update since Current and new Image are identical
+		result.rows.AppendRow([]interface{}{
+			aService.Name,
+			currentRevision,
+			"Unmodified",
+			deployment.currentImage,
+			deployment.desiredImage,
+			aService.Status,
+			aService.RunningCount})
+		return result
+	}
 
-				if aService.UpdateChildTask {
-					updateChildTasks(cmd, t, &aService)
-				}
+	newServiceTaskDefinition := currentRevision
+	serviceStatus := aService.Status
+	result.history = &gtdhistory.Record{
+		Action:            "deploy",
+		Service:           aService.Name,
+		OldTaskDefinition: currentRevision,
+		OldImage:          deployment.currentImage,
+		NewImage:          deployment.desiredImage,
+		EnvFileHash:       envFilesHash(aService),
+	}
+
+	// secret values of the env files are stored before registering their ARN
+	if deployment.register && deployment.environment != nil && len(deployment.environment.writes) > 0 {
+		arns, err := deployment.environment.writeParams(cmd, aService)
+		if err != nil {
+			result.err = err
+			serviceStatus = "PARAMS FAILED"
+		} else {
+			aService.ParamARNs = arns
+			resolveParams(deployment.input.ContainerDefinitions, arns)
+		}
+	}
+
+	if deployment.register && result.err == nil {
+		registered, err := cmd.AWSSession.Svc.RegisterTaskDefinition(deployment.input)
+		if err != nil {
+			result.err = fmt.Errorf("error while registering task definifition : %s\n%s", *aService.TaskDefinition.Family, err.Error())
+			serviceStatus = "REGISTER FAILED"
+		} else {
+			newServiceTaskDefinition = fmt.Sprintf("%s:%d", *registered.TaskDefinition.Family, *registered.TaskDefinition.Revision)
+		}
+	}
+
+	// pre_deploy hooks run with the new revision, the service is only updated when they succeed
+	hookRows := &deployRows{}
+	if result.err == nil && len(aService.Hooks.PreDeploy) > 0 {
+		if err := runHooks(cmd, hookRows, aService, "pre_deploy", aService.Hooks.PreDeploy, newServiceTaskDefinition); err != nil {
+			result.err = err
+			serviceStatus = "PRE-DEPLOY FAILED"
+		}
+	}
+
+	//Update Service
+	if result.err == nil {
+		if _, err := cmd.AWSSession.UpdateAWSService(cmd.AWSSession.Svc, &aService.Name, &cmd.Services.ECSCluster, &newServiceTaskDefinition, forceDeploy); err != nil {
+			result.err = fmt.Errorf("error while updating service: %s\n %s", aService.Name, err.Error())
+			serviceStatus = "UPDATE FAILED"
+		} else {
+			result.taskDefinition = newServiceTaskDefinition
+			result.previousRevision = currentRevision
+		}
+	}
+
+	result.rows.AppendRow([]interface{}{
+		aService.Name,
+		currentRevision,
+		newServiceTaskDefinition,
+		deployment.currentImage,
+		deployment.desiredImage,
+		serviceStatus,
+		aService.RunningCount})
+	for _, row := range hookRows.rows {
+		result.rows.AppendRow(row)
+	}
+
+	if result.err != nil {
+		return result
+	}
+
+	//Ok we have updated service
+	//But do we need to publish a ECR, or push Image with another name ?
+	if !strings.EqualFold("", aService.UpdateECR) {
+		// docker progress is printed, one service at a time
+		dockerOutput.Lock()
+		err := publishRegistry(cmd, &result.rows, aService, deployment.desiredImage)
+		dockerOutput.Unlock()
+		if err != nil {
+			result.err = err
+		}
+	}
+
+	if aService.UpdateChildTask {
+		children, err := updateChildTasks(cmd, &result.rows, deployment)
+		result.children = children
+		if err != nil && result.err == nil {
+			result.err = err
+		}
+	}
+
+	return result
+}
+
+//runPostDeployHooks run the post_deploy hooks of an updated service
+func runPostDeployHooks(cmd *Command, result *serviceResult) {
+	if len(result.service.Hooks.PostDeploy) == 0 {
+		return
+	}
+	if err := runHooks(cmd, &result.rows, result.service, "post_deploy", result.service.Hooks.PostDeploy, result.taskDefinition); err != nil {
+		result.err = err
+		result.rows.rows[0][5] = fmt.Sprintf("%v (post_deploy failed)", result.rows.rows[0][5])
+	}
+}
+
+//envFilesHash Return the hash of the environment files of aService
+func envFilesHash(aService *config.Service) string {
+	files := make([]string, 0, len(aService.EnvFiles)+1)
+	for _, file := range aService.EnvFiles {
+		path, err := homedir.Expand(file)
+		if err != nil {
+			path = file
+		}
+		files = append(files, path)
+	}
+	if !strings.EqualFold("", environmentFilePath) {
+		files = append(files, environmentFilePath)
+	}
+	return fileHash(files...)
+}
+
+//recordDeploy add the updated services of a stage to the history,
+//with their final status (after wait, bake and rollback)
+func recordDeploy(cmd *Command, results []*serviceResult) {
+	records := make([]gtdhistory.Record, 0, len(results))
+	for _, result := range results {
+		if result.history == nil {
+			continue
+		}
+		result.history.NewTaskDefinition = result.taskDefinition
+		result.history.Result = fmt.Sprint(result.rows.rows[0][5])
+		records = append(records, *result.history)
+	}
+	cmd.RecordHistory(records...)
+}
+
+//deployChanges summarize results for the notifications
+func deployChanges(results []*serviceResult) []gtdnotify.Change {
+	changes := make([]gtdnotify.Change, 0, len(results))
+	for _, result := range results {
+		change := gtdnotify.Change{
+			Service:           result.service.Name,
+			OldTaskDefinition: fmt.Sprint(result.rows.rows[0][1]),
+			Status:            fmt.Sprint(result.rows.rows[0][5]),
+		}
+		if result.history != nil {
+			change.NewTaskDefinition = result.taskDefinition
+			change.OldImage = result.history.OldImage
+			change.NewImage = result.history.NewImage
+		}
+		if result.err != nil {
+			change.Error = result.err.Error()
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+//skippedResult report a service left untouched
+func skippedResult(aService *config.Service, status string) *serviceResult {
+	result := &serviceResult{service: aService}
+	result.rows.AppendRow([]interface{}{
+		aService.Name,
+		fmt.Sprintf("%s:%d", *aService.TaskDefinition.Family, *aService.TaskDefinition.Revision),
+		"Unmodified",
+		containerImages(aService.TaskDefinition.ContainerDefinitions),
+		"-",
+		status,
+		aService.RunningCount})
+	return result
+}
+
+//serviceNames Return the names of services
+func serviceNames(services []*config.Service) []string {
+	names := make([]string, 0, len(services))
+	for _, aService := range services {
+		names = append(names, aService.Name)
+	}
+	return names
+}
+
+//waitResults wait for every updated service to stabilize,
+//and rollback the failing ones when asked to.
+func waitResults(cmd *Command, results []*serviceResult) {
+	waitTargets := make(map[string]string)
+	for _, result := range results {
+		if !strings.EqualFold("", result.taskDefinition) {
+			waitTargets[result.service.Name] = result.taskDefinition
+		}
+	}
+
+	failures := waitForServices(cmd, waitTargets, waitTimeout)
+
+	for _, result := range results {
+		if strings.EqualFold("", result.taskDefinition) {
+			continue
+		}
+
+		err, failed := failures[result.service.Name]
+		if !failed {
+			result.rows.rows[0][5] = "STABLE"
+			runPostDeployHooks(cmd, result)
+			continue
+		}
+
+		result.err = err
+		result.rows.rows[0][5] = "FAILED"
+		rollbackResult(cmd, result)
+	}
+}
+
+//rollbackResult rollback a failed service and its child tasks
+//when auto rollback is enabled
+func rollbackResult(cmd *Command, result *serviceResult) {
+	if !autoRollback {
+		return
+	}
+
+	result.rows.rows[0][5] = rollbackService(cmd, result.service.Name, result.previousRevision)
+	for _, child := range result.children {
+		result.rows.rows[child.row][2] = rollbackChildTask(cmd, child.taskDefinition)
+	}
+}
+
+//withCanaryStage move the canary service out of its wave, into a stage of its own
+//deployed after the waves it depends on and before the rest of its wave.
+//It returns the stages and the index of the canary's stage.
+func withCanaryStage(stages [][]*config.Service, canary string) ([][]*config.Service, int, error) {
+	canaryStage := -1
+	withCanary := make([][]*config.Service, 0, len(stages)+1)
+
+	for _, stage := range stages {
+		rest := make([]*config.Service, 0, len(stage))
+		for _, aService := range stage {
+			if strings.EqualFold(aService.Name, canary) {
+				canaryStage = len(withCanary)
+				withCanary = append(withCanary, []*config.Service{aService})
 			} else {
-				// Skipping Update since Current and new Image are identical
-				t.AppendRow([]interface{}{
-					aService.Name,
-					fmt.Sprintf("%s:%d", *aService.TaskDefinition.Family, *aService.TaskDefinition.Revision),
-					newServiceTaskDefinition,
-					*aService.TaskDefinition.ContainerDefinitions[0].Image,
-					fmt.Sprintf("%s%s", newContainerImage, newContainerTag),
-					aService.Status,
-					aService.RunningCount})
+				rest = append(rest, aService)
 			}
 		}
+		if len(rest) > 0 {
+			withCanary = append(withCanary, rest)
+		}
+	}
+
+	if canaryStage < 0 {
+		return nil, -1, fmt.Errorf("canary %s is not one of the services to deploy", canary)
+	}
+	return withCanary, canaryStage, nil
+}
+
+//bakeCanary let the canary run for --bake,
+//then check its deployment is still stable
+func bakeCanary(cmd *Command, result *serviceResult) {
+	fmt.Printf("Baking canary %s for %s\n", result.service.Name, canaryBake)
+	time.Sleep(canaryBake)
+
+	service, err := cmd.AWSSession.DescribeAWSService(cmd.AWSSession.Svc, &result.service.Name, &cmd.Services.ECSCluster)
+	if err == nil {
+		state := serviceDeploymentState(cmd, service, result.taskDefinition)
+		switch {
+		case state.Failed:
+			err = fmt.Errorf("canary failed during bake: %s", state.Reason)
+		case state.RunningCount < state.DesiredCount:
+			err = fmt.Errorf("canary running %d/%d tasks after bake", state.RunningCount, state.DesiredCount)
+		}
+	}
+
+	if err != nil {
+		result.err = err
+		result.rows.rows[0][5] = "FAILED (bake)"
+		rollbackResult(cmd, result)
+	}
+}
+
+//renderDeployTable print the rows of every service deployed
+func renderDeployTable(cmd *Command, results []*serviceResult) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Service", "Current Revision", "New Revision", "Current Image", "Desired Image", "status", "Running count"})
+
+	for _, result := range results {
+		for _, row := range result.rows.rows {
+			t.AppendRow(row)
+		}
 	}
 
 	// t.SetAllowedColumnLengths([]int{10, -1, 10, 10, 10, 10})
@@ -248,48 +657,55 @@ func deployServices(cmd *Command) {
 	t.Render()
 }
 
-func updateChildTasks(cmd *Command, tab interface{}, aService *config.Service) {
+//updateChildTasks register a new revision of each child task of the deployed service
+//and return the revisions registered, with the first error met.
+func updateChildTasks(cmd *Command, tab *deployRows, deployment *serviceDeployment) ([]childRegistration, error) {
+	aService := deployment.service
 	var statusChildTask, currentImage, currentTaskRevision string
+	var firstErr error
 	goretPic := "🐺"
+	registrations := make([]childRegistration, 0)
 
 	for _, t := range cmd.ChildTasks.ChildTasks {
+		var registered string
 
 		if strings.EqualFold(t.ParentService, aService.Name) && !t.IgnoreDeploy {
 			taskDefinition, err := cmd.AWSSession.GetCurrentTaskDefinition(cmd.AWSSession.Svc, t.Name)
 			if err != nil {
-				log.Fatal("error while getting child task definition")
-			}
+				err = fmt.Errorf("error while getting child task definition: %v", err)
+				currentTaskRevision = "-"
+				currentImage = "-"
+			} else {
+				currentTaskRevision = fmt.Sprintf("%s:%d", *taskDefinition.TaskDefinition.Family, *taskDefinition.TaskDefinition.Revision)
+				if containers, err := gtdaws.SelectContainerDefinitions(taskDefinition.TaskDefinition.ContainerDefinitions, childTaskContainers(t)...); err == nil {
+					currentImage = containerImages(containers)
+				}
 
-			currentImage = *taskDefinition.TaskDefinition.ContainerDefinitions[0].Image
-			currentTaskRevision = fmt.Sprintf("%s:%d", *taskDefinition.TaskDefinition.Family, *taskDefinition.TaskDefinition.Revision)
-			taskDefinition.TaskDefinition.ContainerDefinitions[0].Image = aws.String(fmt.Sprintf("%s%s", newContainerImage, newContainerTag))
-
-			input := &ecs.RegisterTaskDefinitionInput{
-				Family:                  taskDefinition.TaskDefinition.Family,
-				ContainerDefinitions:    taskDefinition.TaskDefinition.ContainerDefinitions,
-				TaskRoleArn:             taskDefinition.TaskDefinition.TaskRoleArn,
-				ExecutionRoleArn:        taskDefinition.TaskDefinition.ExecutionRoleArn,
-				Memory:                  taskDefinition.TaskDefinition.Memory,
-				NetworkMode:             taskDefinition.TaskDefinition.NetworkMode,
-				RequiresCompatibilities: taskDefinition.TaskDefinition.RequiresCompatibilities,
-				Cpu:                     taskDefinition.TaskDefinition.Cpu,
-				Volumes:                 taskDefinition.TaskDefinition.Volumes,
+				var input *ecs.RegisterTaskDefinitionInput
+				input, err = newChildTaskInput(taskDefinition, t, deployment)
+				if err == nil {
+					newChildTaskDefinition, registerErr := cmd.AWSSession.Svc.RegisterTaskDefinition(input)
+					if registerErr == nil {
+						statusChildTask = fmt.Sprintf("%s:%d", *newChildTaskDefinition.TaskDefinition.Family, *newChildTaskDefinition.TaskDefinition.Revision)
+					}
+					err = registerErr
+				}
 			}
-			input.ContainerDefinitions[0].SetEnvironment(aService.TasksEnv)
-			input.ContainerDefinitions[0].SetSecrets(aService.SecretsEnv)
-
-			newChildTaskDefinition, err := cmd.AWSSession.Svc.RegisterTaskDefinition(input)
 			if err != nil {
 				statusChildTask = fmt.Sprintf("Error on %s: %v", t.Name, err)
+				goretPic = "🐺"
+				if firstErr == nil {
+					firstErr = fmt.Errorf("child task %s: %v", t.Name, err)
+				}
 			} else {
-				statusChildTask = fmt.Sprintf("%s:%d", *newChildTaskDefinition.TaskDefinition.Family, *newChildTaskDefinition.TaskDefinition.Revision)
+				registered = statusChildTask
 				goretPic = "🐷"
 			}
 		} else {
 			statusChildTask = "Ignored"
 			goretPic = "💤"
 		}
-		tab.(table.Writer).AppendRow([]interface{}{
+		row := tab.AppendRow([]interface{}{
 			fmt.Sprintf(" ↳ %s", t.Name),
 			currentTaskRevision,
 			statusChildTask,
@@ -297,57 +713,105 @@ func updateChildTasks(cmd *Command, tab interface{}, aService *config.Service) {
 			fmt.Sprintf("same as %s", aService.Name),
 			goretPic,
 			"-"})
+
+		if !strings.EqualFold("", registered) {
+			registrations = append(registrations, childRegistration{row: row, taskDefinition: registered})
+		}
+	}
+	return registrations, firstErr
+}
+
+//rollbackService point serviceName back to the revision
+//it was using before the deploy.
+//It returns the status to display.
+func rollbackService(cmd *Command, serviceName, previousRevision string) string {
+	if strings.EqualFold("", previousRevision) {
+		return "FAILED (no previous revision)"
+	}
+
+	log.Printf("Rolling back %s to %s", serviceName, previousRevision)
+	_, err := cmd.AWSSession.UpdateAWSService(cmd.AWSSession.Svc, &serviceName, &cmd.Services.ECSCluster, &previousRevision, false)
+	if err != nil {
+		log.Println(fmt.Errorf("error while rolling back service: %s\n %s", serviceName, err.Error()))
+		return "FAILED (rollback failed)"
+	}
+	return fmt.Sprintf("ROLLED BACK to %s", previousRevision)
+}
+
+//rollbackChildTask deregister a child task revision registered by the deploy,
+//so the family falls back to its previous ACTIVE revision.
+//It returns the status to display.
+func rollbackChildTask(cmd *Command, taskDefinition string) string {
+	if err := cmd.AWSSession.DeregisterAWSTaskDefinition(cmd.AWSSession.Svc, &taskDefinition); err != nil {
+		return fmt.Sprintf("%s (rollback failed)", taskDefinition)
 	}
+	return fmt.Sprintf("%s (deregistered)", taskDefinition)
 }
 
-func publishRegistry(cmd *Command, t interface{}, aService *config.Service) {
+//publishRegistry push image to the ECR repository of aService,
+//and Return the first pull, tag or push error
+func publishRegistry(cmd *Command, t *deployRows, aService *config.Service, image string) error {
 	statusChildRegistry := "-"
 	goretPic := "🐺"
 	var RepositoryNameOnly, RepositoryTag, FullURISeparator string
+	var publishErr error
 
-	fmt.Printf("Service name (Source): %s\nImage: %s\n", aService.Name, *aService.TaskDefinition.ContainerDefinitions[0].Image)
+	fmt.Printf("Service name (Source): %s\nImage: %s\n", aService.Name, image)
 
 	for _, r := range cmd.Repositories.Repositories {
 
 		if strings.EqualFold(r.Name, aService.UpdateECR) && !r.IgnoreDeploy {
 
-			if gtddocker.PullFromPrivateRegistry(cmd.DockerHubAuthConfig, *aService.TaskDefinition.ContainerDefinitions[0].Image) {
+			if !gtddocker.PullFromPrivateRegistry(cmd.DockerHubAuthConfig, image) {
+				statusChildRegistry = "Pull failed"
+				publishErr = fmt.Errorf("%s: pull of %s failed", aService.UpdateECR, image)
+			} else {
 				RepositoryUri := cmd.AWSSession.DescribeRepository(r.RepositoryName)
-
-				//if ok := len(strings.Split(r.RepositoryName, ":")); ok > 1 {
-				repositoryParts := strings.SplitN(r.RepositoryName, ":", 2)
-				if len(repositoryParts) == 2 {
-					RepositoryNameOnly = repositoryParts[0]
-					RepositoryTag = repositoryParts[1]
+				if RepositoryUri == nil {
+					statusChildRegistry = fmt.Sprintf("Repository %s not found", r.RepositoryName)
+					publishErr = fmt.Errorf("%s: repository %s not found", aService.UpdateECR, r.RepositoryName)
 				} else {
-					RepositoryNameOnly = repositoryParts[0]
-				}
+					//if ok := len(strings.Split(r.RepositoryName, ":")); ok > 1 {
+					repositoryParts := strings.SplitN(r.RepositoryName, ":", 2)
+					if len(repositoryParts) == 2 {
+						RepositoryNameOnly = repositoryParts[0]
+						RepositoryTag = repositoryParts[1]
+					} else {
+						RepositoryNameOnly = repositoryParts[0]
+					}
 
-				//RepositoryTag = fmt.Sprintf(":%s", strings.Split(r.RepositoryName, ":")[1])
-				//}
-				if !strings.EqualFold("", RepositoryTag) {
-					FullURISeparator = ":"
-				} else {
-					FullURISeparator = ":"
-					RepositoryTag = "latest"
-				}
+					//RepositoryTag = fmt.Sprintf(":%s", strings.Split(r.RepositoryName, ":")[1])
+					//}
+					if !strings.EqualFold("", RepositoryTag) {
+						FullURISeparator = ":"
+					} else {
+						FullURISeparator = ":"
+						RepositoryTag = "latest"
+					}
 
-				fullURI := fmt.Sprintf("%s%s%s", *RepositoryUri.RepositoryUri, FullURISeparator, RepositoryTag)
+					fullURI := fmt.Sprintf("%s%s%s", *RepositoryUri.RepositoryUri, FullURISeparator, RepositoryTag)
 
-				gtddocker.TagLocalDockerImageFrom(*aService.TaskDefinition.ContainerDefinitions[0].Image, fullURI)
-				statusChildRegistry = "Tagged Locally (Only)"
+					if err := gtddocker.TagLocalDockerImageFrom(image, fullURI); err != nil {
+						statusChildRegistry = fmt.Sprintf("Tag failed: %v", err)
+						publishErr = fmt.Errorf("%s: tag of %s failed: %v", aService.UpdateECR, fullURI, err)
+					} else {
+						statusChildRegistry = "Tagged Locally (Only)"
 
-				//then push to ecr
-				if cmd.AWSSession.PushToECR(RepositoryNameOnly, RepositoryTag, fullURI) {
-					statusChildRegistry = fmt.Sprintf("Pushed on %s", fullURI)
-					goretPic = "🐷"
+						//then push to ecr
+						if cmd.AWSSession.PushToECR(RepositoryNameOnly, RepositoryTag, fullURI) {
+							statusChildRegistry = fmt.Sprintf("Pushed on %s", fullURI)
+							goretPic = "🐷"
+						} else {
+							publishErr = fmt.Errorf("%s: push of %s failed", aService.UpdateECR, fullURI)
+						}
+					}
 				}
 			}
 		} else {
 			statusChildRegistry = "Ignored"
 			goretPic = "💤"
 		}
-		t.(table.Writer).AppendRow([]interface{}{
+		t.AppendRow([]interface{}{
 			fmt.Sprintf(" ↳ %s", aService.UpdateECR),
 			"-",
 			statusChildRegistry,
@@ -356,4 +820,5 @@ func publishRegistry(cmd *Command, t interface{}, aService *config.Service) {
 			goretPic,
 			"-"})
 	}
+	return publishErr
 }
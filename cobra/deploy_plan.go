@@ -0,0 +1,589 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	gtdaws "github.com/gpkfr/goretdep/aws"
+	"github.com/gpkfr/goretdep/config"
+	"github.com/gpkfr/goretdep/gtddocker"
+)
+
+//serviceDeployment describe what a deploy changes on a service
+type serviceDeployment struct {
+	service *config.Service
+	// names of the containers updated
+	containers   []string
+	currentImage string
+	desiredImage string
+	// image of each updated container, by name
+	images       map[string]string
+	imageChanged bool
+	// the service will be updated
+	update bool
+	// a new revision has to be registered with input
+	register bool
+	input    *ecs.RegisterTaskDefinitionInput
+	// environment files applied, nil when none
+	environment *taskEnvironment
+}
+
+//envChange describe the change of one variable (or label)
+type envChange struct {
+	action string
+	name   string
+	from   string
+	to     string
+}
+
+//desiredImage compute the image to deploy on aService
+//from --container-image and --tag
+func desiredImage(aService *config.Service, currentImage string) (string, error) {
+	// set by promote, the image comes from another env
+	if !strings.EqualFold("", aService.DesiredImage) {
+		if pinned, ok := pinnedImages[aService.DesiredImage]; ok {
+			return pinned, nil
+		}
+		return aService.DesiredImage, nil
+	}
+
+	image := newContainerImage
+	tag := newContainerTag
+
+	//very Specific to Deploy
+	// Check if we use the current image definition
+	if image == "" {
+		image = aService.Registry
+		if tag == "" && forceDeploy {
+			image = currentImage
+		}
+	}
+
+	if tag != "" {
+		if strings.Contains(image, ":") {
+			return "", fmt.Errorf("Tags already defined in %s", image)
+		}
+		if !strings.Contains(tag, ":") {
+			//Add missing colon
+			tag = fmt.Sprintf(":%s", tag)
+		}
+	}
+
+	if pinned, ok := pinnedImages[fmt.Sprintf("%s%s", image, tag)]; ok {
+		return pinned, nil
+	}
+	return fmt.Sprintf("%s%s", image, tag), nil
+}
+
+//pinnedImages map a requested image to the image pinned by digest (repo:tag@sha256:...)
+var pinnedImages = make(map[string]string)
+
+//verifyImages check that the new image of every service exists in its registry
+//(ECR or registry v2 API). The deploy is aborted before anything is registered
+//when one is missing. With pin, the digest of every image is kept in pinnedImages.
+func verifyImages(cmd *Command, services []*config.Service, pin bool) {
+	registry := gtddocker.NewRegistryClient(cmd.DockerHubAuthConfig)
+	checked := make(map[string]error)
+	missing := make([]string, 0)
+
+	for _, aService := range services {
+		deployment, err := newImageDeployment(aService)
+		if err != nil || !deployment.update {
+			// errors are reported by the deploy of the service
+			continue
+		}
+		current, _ := gtdaws.SelectContainerDefinitions(aService.TaskDefinition.ContainerDefinitions, deployment.containers...)
+		for _, container := range current {
+			image := deployment.images[aws.StringValue(container.Name)]
+			if !pin && strings.EqualFold(image, aws.StringValue(container.Image)) {
+				continue
+			}
+
+			if _, done := checked[image]; !done {
+				digest, err := imageDigest(cmd, registry, image)
+				checked[image] = err
+				if err == nil && pin && strings.EqualFold("", gtddocker.Digest(image)) {
+					pinnedImages[image] = fmt.Sprintf("%s@%s", image, digest)
+				}
+			}
+			if err := checked[image]; err != nil {
+				missing = append(missing, fmt.Sprintf("%s: %v", aService.Name, err))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		log.Fatalf("Image check failed, nothing was deployed:\n%s", strings.Join(missing, "\n"))
+	}
+}
+
+//imageDigest return the digest of image, or an error when it cannot be found
+func imageDigest(cmd *Command, registry *gtddocker.RegistryClient, image string) (string, error) {
+	var digest string
+	var err error
+	if gtdaws.IsECRImage(image) {
+		digest, err = cmd.AWSSession.ECRImageDigest(image)
+	} else {
+		digest, err = registry.ImageDigest(image)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to check image %s: %v", image, err)
+	}
+	if strings.EqualFold("", digest) {
+		return "", fmt.Errorf("image %s not found", image)
+	}
+	return digest, nil
+}
+
+//deployments is the deployment of each service, computed once by planDeployments
+//so the checks, the plan and the deploy never disagree
+type deployments map[*config.Service]*plannedDeployment
+
+//plannedDeployment is the deployment of a service, or the error computing it
+type plannedDeployment struct {
+	deployment *serviceDeployment
+	err        error
+}
+
+//planDeployments compute the deployment of every service,
+//reading their environment files once
+func planDeployments(services []*config.Service) deployments {
+	planned := make(deployments, len(services))
+	for _, aService := range services {
+		deployment, err := newServiceDeployment(aService)
+		planned[aService] = &plannedDeployment{deployment: deployment, err: err}
+	}
+	return planned
+}
+
+//newImageDeployment compute the images a deploy sets on aService,
+//without reading its environment files nor calling AWS.
+func newImageDeployment(aService *config.Service) (*serviceDeployment, error) {
+	containers, err := gtdaws.SelectContainerDefinitions(aService.TaskDefinition.ContainerDefinitions, serviceContainers(aService)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", aService.Name, err)
+	}
+
+	deployment := &serviceDeployment{
+		service:      aService,
+		containers:   containerNames(containers),
+		currentImage: containerImages(containers),
+	}
+
+	image, err := desiredImage(aService, aws.StringValue(containers[0].Image))
+	if err != nil {
+		return nil, err
+	}
+	deployment.images, err = targetImages(containers, image, keepCurrentImages(aService))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", aService.Name, err)
+	}
+	deployment.desiredImage = deployment.images[deployment.containers[0]]
+
+	for _, container := range containers {
+		if !strings.EqualFold(aws.StringValue(container.Image), deployment.images[aws.StringValue(container.Name)]) {
+			deployment.imageChanged = true
+		}
+	}
+
+	//update tasks
+	deployment.update = forceDeploy || deployment.imageChanged
+	return deployment, nil
+}
+
+//newServiceDeployment compute the task definition a deploy would register
+//for aService, without calling AWS.
+func newServiceDeployment(aService *config.Service) (*serviceDeployment, error) {
+	deployment, err := newImageDeployment(aService)
+	if err != nil || !deployment.update {
+		return deployment, err
+	}
+
+	var isEnvFile bool = false
+
+	// work on a copy, the current task definition is kept for comparison
+	input := gtdaws.NewRegisterTaskDefinitionInput(aService.TaskDefinition, aService.TaskDefinitionTags)
+	targets, _ := gtdaws.SelectContainerDefinitions(input.ContainerDefinitions, deployment.containers...)
+
+	if aService.TaskDefinition.TaskRoleArn != nil {
+		if !strings.EqualFold(aService.TaskRoleArn, *aService.TaskDefinition.TaskRoleArn) {
+			input.SetTaskRoleArn(aService.TaskRoleArn)
+			isEnvFile = true
+		}
+	} else {
+		if !strings.EqualFold("", aService.TaskRoleArn) {
+			input.SetTaskRoleArn(aService.TaskRoleArn)
+			isEnvFile = true
+		}
+	}
+
+	if aService.TaskDefinition.ExecutionRoleArn != nil {
+		// si les valeurs sont différrente / Mis à jour à partir du fichier de config
+		if !strings.EqualFold(aService.TaskExecutionRoleArn, *aService.TaskDefinition.ExecutionRoleArn) {
+			input.SetExecutionRoleArn(aService.TaskExecutionRoleArn)
+			isEnvFile = true
+		} else {
+			input.SetExecutionRoleArn(aService.TaskExecutionRoleArn)
+		}
+
+	} else {
+		if !strings.EqualFold("", aService.TaskExecutionRoleArn) {
+			input.SetExecutionRoleArn(aService.TaskExecutionRoleArn)
+			isEnvFile = true
+		}
+	}
+
+	//Need to read the environment Files
+	environment, err := serviceEnvironment(aService)
+	if err != nil {
+		return nil, err
+	}
+	if environment != nil {
+		isEnvFile = true
+		deployment.environment = environment
+
+		for _, target := range targets {
+			environment.apply(target)
+		}
+		aService.TasksEnv = targets[0].Environment
+		aService.SecretsEnv = targets[0].Secrets
+		if len(targets[0].Secrets) > 0 {
+			input.SetExecutionRoleArn(aService.TaskExecutionRoleArn)
+		}
+	}
+
+	labels := make(map[string]*string)
+
+	if len(aService.Labels) > 0 {
+		for _, label := range aService.Labels {
+			value := label.Value
+			labels[label.Key] = &value
+		}
+	}
+
+	for _, target := range targets {
+		if eq := reflect.DeepEqual(target.DockerLabels, labels); !eq {
+			target.SetDockerLabels(labels)
+			isEnvFile = true
+		}
+	}
+
+	if isEnvFile || deployment.imageChanged {
+		for _, target := range targets {
+			target.SetImage(deployment.images[aws.StringValue(target.Name)])
+		}
+		deployment.register = true
+		deployment.input = input
+	}
+
+	return deployment, nil
+}
+
+//newChildTaskInput compute the revision of a child task
+//registered along the deployment of its parent service
+func newChildTaskInput(taskDefinition *ecs.DescribeTaskDefinitionOutput, childTask config.ChildTask, deployment *serviceDeployment) (*ecs.RegisterTaskDefinitionInput, error) {
+	aService := deployment.service
+	input := gtdaws.NewRegisterTaskDefinitionInput(taskDefinition.TaskDefinition, taskDefinition.Tags)
+
+	targets, err := gtdaws.SelectContainerDefinitions(input.ContainerDefinitions, childTaskContainers(childTask)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", childTask.Name, err)
+	}
+
+	// child tasks share the environment files of their parent, when it has some
+	environment := deployment.environment
+
+	images, err := targetImages(targets, deployment.desiredImage, keepCurrentImages(aService))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", childTask.Name, err)
+	}
+
+	for _, target := range targets {
+		target.SetImage(images[aws.StringValue(target.Name)])
+		if environment != nil {
+			environment.apply(target)
+		}
+	}
+	resolveParams(targets, aService.ParamARNs)
+	return input, nil
+}
+
+//planServices print the changes a deploy would make on each service.
+//Nothing is registered nor updated.
+func planServices(cmd *Command) {
+	fmt.Printf("Deploy plan for %s (cluster %s)\n\n", cmd.GTenv, cmd.Services.ECSCluster)
+
+	for i := range cmd.Services.Services {
+		aService := &cmd.Services.Services[i]
+		if aService.TaskDefinition == nil {
+			continue
+		}
+
+		deployment, err := newServiceDeployment(aService)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printServicePlan(cmd, deployment)
+	}
+}
+
+//printServicePlan print the diff between the running task definition
+//of a service and the one the deploy would register
+func printServicePlan(cmd *Command, deployment *serviceDeployment) {
+	aService := deployment.service
+	currentRevision := fmt.Sprintf("%s:%d", *aService.TaskDefinition.Family, *aService.TaskDefinition.Revision)
+
+	if !deployment.update {
+		fmt.Printf("= %s (%s) unchanged, already running %s\n\n", aService.Name, currentRevision, deployment.currentImage)
+		return
+	}
+
+	if !deployment.register {
+		fmt.Printf("~ %s (%s) force new deployment of the current revision\n", aService.Name, currentRevision)
+	} else {
+		fmt.Printf("~ %s (%s -> new revision)\n", aService.Name, currentRevision)
+		printTaskDefinitionDiff("    ", aService.TaskDefinition, deployment.input)
+	}
+
+	if !strings.EqualFold("", aService.UpdateECR) {
+		for _, r := range cmd.Repositories.Repositories {
+			if !strings.EqualFold(r.Name, aService.UpdateECR) {
+				continue
+			}
+			if r.IgnoreDeploy {
+				fmt.Printf("    ↳ %s: ignored\n", r.Name)
+			} else {
+				fmt.Printf("    ↳ %s: publish %s to ECR %s\n", r.Name, deployment.desiredImage, r.RepositoryName)
+			}
+		}
+	}
+
+	if aService.UpdateChildTask {
+		for _, t := range cmd.ChildTasks.ChildTasks {
+			if !strings.EqualFold(t.ParentService, aService.Name) {
+				continue
+			}
+			if t.IgnoreDeploy {
+				fmt.Printf("    ↳ %s: ignored\n", t.Name)
+				continue
+			}
+
+			taskDefinition, err := cmd.AWSSession.GetCurrentTaskDefinition(cmd.AWSSession.Svc, t.Name)
+			if err != nil {
+				fmt.Printf("    ↳ %s: error while getting child task definition\n", t.Name)
+				continue
+			}
+			input, err := newChildTaskInput(taskDefinition, t, deployment)
+			if err != nil {
+				fmt.Printf("    ↳ %s: %v\n", t.Name, err)
+				continue
+			}
+			fmt.Printf("    ↳ %s (%s:%d -> new revision)\n", t.Name, *taskDefinition.TaskDefinition.Family, *taskDefinition.TaskDefinition.Revision)
+			printTaskDefinitionDiff("        ", taskDefinition.TaskDefinition, input)
+		}
+	}
+	fmt.Println()
+}
+
+//verifySecrets check that every secret referenced by the task definitions
+//to register exists. The deploy is aborted before anything is registered
+//when one is missing.
+func verifySecrets(cmd *Command, services []*config.Service, planned deployments) {
+	checked := make(map[string]error)
+	missing := make([]string, 0)
+
+	for _, aService := range services {
+		deployment, err := planned[aService].deployment, planned[aService].err
+		if err != nil || !deployment.register {
+			continue
+		}
+
+		containers, _ := gtdaws.SelectContainerDefinitions(deployment.input.ContainerDefinitions, deployment.containers...)
+		for _, container := range containers {
+			for _, secret := range container.Secrets {
+				valueFrom := aws.StringValue(secret.ValueFrom)
+				name := aws.StringValue(secret.Name)
+				if deployment.environment != nil {
+					if _, written := deployment.environment.writes[name]; written {
+						continue
+					}
+				}
+
+				if _, done := checked[valueFrom]; !done {
+					exists, err := cmd.AWSSession.ParamExists(valueFrom)
+					if err == nil && !exists {
+						err = fmt.Errorf("%s not found", valueFrom)
+					}
+					checked[valueFrom] = err
+				}
+				if err := checked[valueFrom]; err != nil {
+					missing = append(missing, fmt.Sprintf("%s: secret %s: %v", aService.Name, name, err))
+				}
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		log.Fatalf("Secret check failed, nothing was deployed:\n%s", strings.Join(missing, "\n"))
+	}
+}
+
+//confirmEnvironmentChanges print the environment and secrets changes
+//the environment files make on services. Removing variables needs
+//a confirmation, unless --yes.
+func confirmEnvironmentChanges(services []*config.Service, planned deployments) bool {
+	var removed bool
+
+	for _, aService := range services {
+		deployment, err := planned[aService].deployment, planned[aService].err
+		if err != nil || !deployment.register || deployment.environment == nil {
+			// errors are reported by the deploy of the service
+			continue
+		}
+
+		current, _ := gtdaws.SelectContainerDefinitions(aService.TaskDefinition.ContainerDefinitions, deployment.containers...)
+		desired, _ := gtdaws.SelectContainerDefinitions(deployment.input.ContainerDefinitions, deployment.containers...)
+		for i := range desired {
+			envChanges := diffEnvironment(current[i].Environment, desired[i].Environment)
+			secretChanges := diffSecrets(current[i].Secrets, desired[i].Secrets)
+			if len(envChanges) == 0 && len(secretChanges) == 0 {
+				continue
+			}
+
+			fmt.Printf("~ %s (container %s)\n", aService.Name, aws.StringValue(desired[i].Name))
+			printChanges("    ", "environment", envChanges)
+			printChanges("    ", "secrets", secretChanges)
+			for _, change := range append(envChanges, secretChanges...) {
+				if change.action == "-" {
+					removed = true
+				}
+			}
+		}
+	}
+
+	if !removed || assumeYes {
+		return true
+	}
+	return confirm("Some variables will be removed, deploy anyway?")
+}
+
+//printTaskDefinitionDiff print what input changes on current.
+//Environment values are masked, secrets show their references.
+func printTaskDefinitionDiff(indent string, current *ecs.TaskDefinition, input *ecs.RegisterTaskDefinitionInput) {
+	printValueDiff(indent, "task role", aws.StringValue(current.TaskRoleArn), aws.StringValue(input.TaskRoleArn))
+	printValueDiff(indent, "execution role", aws.StringValue(current.ExecutionRoleArn), aws.StringValue(input.ExecutionRoleArn))
+
+	// input is a copy of current, containers are in the same order
+	for i, newContainer := range input.ContainerDefinitions {
+		currentContainer := current.ContainerDefinitions[i]
+		containerIndent := indent
+		if len(input.ContainerDefinitions) > 1 {
+			if reflect.DeepEqual(currentContainer, newContainer) {
+				continue
+			}
+			fmt.Printf("%scontainer %s:\n", indent, aws.StringValue(newContainer.Name))
+			containerIndent = fmt.Sprintf("%s  ", indent)
+		}
+
+		printValueDiff(containerIndent, "image", aws.StringValue(currentContainer.Image), aws.StringValue(newContainer.Image))
+		printChanges(containerIndent, "environment", diffEnvironment(currentContainer.Environment, newContainer.Environment))
+		printChanges(containerIndent, "secrets", diffSecrets(currentContainer.Secrets, newContainer.Secrets))
+		printChanges(containerIndent, "docker labels", diffValues(aws.StringValueMap(currentContainer.DockerLabels), aws.StringValueMap(newContainer.DockerLabels), false))
+	}
+}
+
+func printValueDiff(indent, name, from, to string) {
+	if strings.EqualFold(from, to) {
+		return
+	}
+	if strings.EqualFold("", from) {
+		from = "(none)"
+	}
+	if strings.EqualFold("", to) {
+		to = "(none)"
+	}
+	fmt.Printf("%s%s: %s -> %s\n", indent, name, from, to)
+}
+
+func printChanges(indent, title string, changes []envChange) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Printf("%s%s:\n", indent, title)
+	for _, change := range changes {
+		switch {
+		case strings.EqualFold("", change.from) && strings.EqualFold("", change.to):
+			fmt.Printf("%s  %s %s\n", indent, change.action, change.name)
+		case change.action == "~":
+			fmt.Printf("%s  %s %s: %s -> %s\n", indent, change.action, change.name, change.from, change.to)
+		case change.action == "+":
+			fmt.Printf("%s  %s %s: %s\n", indent, change.action, change.name, change.to)
+		default:
+			fmt.Printf("%s  %s %s: %s\n", indent, change.action, change.name, change.from)
+		}
+	}
+}
+
+//diffEnvironment compare two environments, values are masked
+func diffEnvironment(current, desired []*ecs.KeyValuePair) []envChange {
+	return diffValues(keyValuePairsToMap(current), keyValuePairsToMap(desired), true)
+}
+
+//diffSecrets compare two secret lists, showing their references
+func diffSecrets(current, desired []*ecs.Secret) []envChange {
+	return diffValues(secretsToMap(current), secretsToMap(desired), false)
+}
+
+//diffValues list added (+), removed (-) and changed (~) keys, sorted by name.
+//Values are left empty when masked.
+func diffValues(current, desired map[string]string, masked bool) []envChange {
+	changes := make([]envChange, 0)
+
+	for name, to := range desired {
+		from, exists := current[name]
+		switch {
+		case !exists:
+			changes = append(changes, envChange{action: "+", name: name, to: to})
+		case from != to:
+			changes = append(changes, envChange{action: "~", name: name, from: from, to: to})
+		}
+	}
+	for name, from := range current {
+		if _, exists := desired[name]; !exists {
+			changes = append(changes, envChange{action: "-", name: name, from: from})
+		}
+	}
+
+	if masked {
+		for i := range changes {
+			changes[i].from = ""
+			changes[i].to = ""
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].name < changes[j].name
+	})
+	return changes
+}
+
+func keyValuePairsToMap(pairs []*ecs.KeyValuePair) map[string]string {
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		values[aws.StringValue(pair.Name)] = aws.StringValue(pair.Value)
+	}
+	return values
+}
+
+func secretsToMap(secrets []*ecs.Secret) map[string]string {
+	values := make(map[string]string, len(secrets))
+	for _, secret := range secrets {
+		values[aws.StringValue(secret.Name)] = aws.StringValue(secret.ValueFrom)
+	}
+	return values
+}
@@ -0,0 +1,217 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/gpkfr/goretdep/gtdhistory"
+	"github.com/spf13/cobra"
+)
+
+//desiredCountTag is the ECS service tag keeping the desired count of a stopped service
+const desiredCountTag = "gtd:desired-count"
+
+//minCapacityTag is the ECS service tag keeping the auto scaling minimum capacity of a stopped service
+const minCapacityTag = "gtd:min-capacity"
+
+//NewEnvCommand bind the env command and its subcommands
+func NewEnvCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "env",
+		Short: "Stop or start every service of an environment",
+	}
+	cobraCmd.PersistentFlags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
+	cobraCmd.PersistentFlags().BoolVar(&waitDeploy, "wait", false, "Wait until the running counts match the desired counts")
+	cobraCmd.PersistentFlags().DurationVar(&waitTimeout, "timeout", 15*time.Minute, "Maximum time to wait for services to stabilize [--timeout 15m]")
+	cobraCmd.PersistentFlags().StringVar(&releaseReason, "reason", "", "Reason, shown to whoever finds the environment locked")
+
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Scale every service to zero, remembering its desired count",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			envStop(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: "Restore the desired count of every service stopped by env stop",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			envStart(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+
+	cobraCmd.AddCommand(stopCmd, startCmd)
+	cmd.AddCommand(cobraCmd)
+}
+
+func envStop(cmd *Command) {
+	scaleEnvironment(cmd, "env stop", func(serviceName string, serviceArn *string, current int64) (int64, string, error) {
+		if current == 0 {
+			return 0, "ALREADY STOPPED", nil
+		}
+		// keep the count before scaling down, start needs it
+		if err := cmd.AWSSession.SetResourceTag(cmd.AWSSession.Svc, serviceArn, desiredCountTag, strconv.FormatInt(current, 10)); err != nil {
+			return current, "", fmt.Errorf("unable to save the desired count: %v", err)
+		}
+
+		// auto scaling would scale the service back to its minimum capacity
+		minCapacity, scalable, err := cmd.AWSSession.GetServiceMinCapacity(cmd.Services.ECSCluster, serviceName)
+		if err != nil {
+			return current, "", fmt.Errorf("unable to read the auto scaling target: %v", err)
+		}
+		if scalable && minCapacity > 0 {
+			if err := cmd.AWSSession.SetResourceTag(cmd.AWSSession.Svc, serviceArn, minCapacityTag, strconv.FormatInt(minCapacity, 10)); err != nil {
+				return current, "", fmt.Errorf("unable to save the auto scaling minimum capacity: %v", err)
+			}
+			if err := cmd.AWSSession.SetServiceMinCapacity(cmd.Services.ECSCluster, serviceName, 0); err != nil {
+				return current, "", fmt.Errorf("unable to set the auto scaling minimum capacity to 0: %v", err)
+			}
+		}
+		return 0, "STOPPED", nil
+	})
+}
+
+func envStart(cmd *Command) {
+	scaleEnvironment(cmd, "env start", func(serviceName string, serviceArn *string, current int64) (int64, string, error) {
+		if err := restoreMinCapacity(cmd, serviceName, serviceArn); err != nil {
+			return current, "", err
+		}
+
+		saved, found, err := cmd.AWSSession.GetResourceTag(cmd.AWSSession.Svc, serviceArn, desiredCountTag)
+		if err != nil {
+			return current, "", err
+		}
+		if !found {
+			return current, "NO SAVED COUNT", nil
+		}
+		if current > 0 {
+			return current, "ALREADY RUNNING", nil
+		}
+
+		count, err := strconv.ParseInt(saved, 10, 64)
+		if err != nil {
+			return current, "", fmt.Errorf("invalid saved count %s", saved)
+		}
+		return count, "STARTED", nil
+	})
+}
+
+//restoreMinCapacity restore the auto scaling minimum capacity saved by env stop
+func restoreMinCapacity(cmd *Command, serviceName string, serviceArn *string) error {
+	saved, found, err := cmd.AWSSession.GetResourceTag(cmd.AWSSession.Svc, serviceArn, minCapacityTag)
+	if err != nil || !found {
+		return err
+	}
+	minCapacity, err := strconv.ParseInt(saved, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid saved minimum capacity %s", saved)
+	}
+	if err := cmd.AWSSession.SetServiceMinCapacity(cmd.Services.ECSCluster, serviceName, minCapacity); err != nil {
+		return fmt.Errorf("unable to restore the auto scaling minimum capacity: %v", err)
+	}
+	if err := cmd.AWSSession.RemoveResourceTag(cmd.AWSSession.Svc, serviceArn, minCapacityTag); err != nil {
+		log.Printf("%s: unable to remove the %s tag: %v", serviceName, minCapacityTag, err)
+	}
+	return nil
+}
+
+//scaleEnvironment apply newCount to every service of the stack file,
+//except the ignored and always_on ones. newCount Return the desired count,
+//the status reported and an error when the service must not be scaled.
+func scaleEnvironment(cmd *Command, action string, newCount func(serviceName string, serviceArn *string, current int64) (int64, string, error)) {
+	cmd.AWSSession.GetServices(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, cmd.GTenv, true)
+
+	release := cmd.AcquireLock(action)
+	defer release()
+
+	rows := &deployRows{}
+	serviceRows := make(map[string]int)
+	waitTargets := make(map[string]string)
+	records := make([]gtdhistory.Record, 0)
+	var failed bool
+
+	for _, aService := range cmd.Services.Services {
+		if aService.TaskDefinition == nil || aService.IgnoreDeploy {
+			continue
+		}
+		if aService.AlwaysOn {
+			rows.AppendRow([]interface{}{aService.Name, "-", "-", aService.RunningCount, "-", "ALWAYS ON"})
+			continue
+		}
+
+		serviceName := aService.Name
+		service, err := cmd.AWSSession.DescribeAWSService(cmd.AWSSession.Svc, &serviceName, &cmd.Services.ECSCluster)
+		if err != nil {
+			failed = true
+			rows.AppendRow([]interface{}{aService.Name, "-", "-", aService.RunningCount, "-", err.Error()})
+			continue
+		}
+		before := aws.Int64Value(service.DesiredCount)
+
+		after, status, err := newCount(serviceName, service.ServiceArn, before)
+		if err != nil {
+			failed = true
+			status = fmt.Sprintf("FAILED (%v)", err)
+		} else if after != before {
+			if _, err := cmd.AWSSession.SetDesiredCount(cmd.AWSSession.Svc, &serviceName, &cmd.Services.ECSCluster, after); err != nil {
+				failed = true
+				status = fmt.Sprintf("FAILED (%v)", err)
+			} else {
+				waitTargets[aService.Name] = ""
+				records = append(records, gtdhistory.Record{
+					Action:  action,
+					Service: aService.Name,
+					Result:  fmt.Sprintf("%d -> %d %s", before, after, status),
+				})
+				// the saved count is used, forget it
+				if after > 0 {
+					if err := cmd.AWSSession.RemoveResourceTag(cmd.AWSSession.Svc, service.ServiceArn, desiredCountTag); err != nil {
+						log.Printf("%s: unable to remove the %s tag: %v", aService.Name, desiredCountTag, err)
+					}
+				}
+			}
+		}
+
+		serviceRows[aService.Name] = rows.AppendRow([]interface{}{
+			aService.Name,
+			before,
+			after,
+			aws.Int64Value(service.RunningCount),
+			"-",
+			status})
+	}
+
+	if waitDeploy {
+		failures := waitForServices(cmd, waitTargets, waitTimeout)
+		for name := range waitTargets {
+			if err, ko := failures[name]; ko {
+				failed = true
+				log.Printf("%s: %v", name, err)
+				rows.rows[serviceRows[name]][5] = "FAILED"
+			}
+		}
+	}
+
+	cmd.RecordHistory(records...)
+	renderScaleTable(cmd, rows, serviceRows)
+
+	if failed {
+		release()
+		os.Exit(1)
+	}
+}
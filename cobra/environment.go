@@ -0,0 +1,204 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	gtdaws "github.com/gpkfr/goretdep/aws"
+	"github.com/gpkfr/goretdep/config"
+	"github.com/mitchellh/go-homedir"
+)
+
+//taskEnvironment is the environment a deploy sets on the containers of a service,
+//read from its env_files then from --config (the last file wins).
+//Keys prefixed with _ are secrets.
+type taskEnvironment struct {
+	files   []string
+	env     map[string]string
+	secrets map[string]string
+	// keys removed in merge mode
+	unset []string
+	// merge into the current environment instead of replacing it
+	merge bool
+	// secret values (_KEY=ssm:VALUE) written before registering, by key
+	writes map[string]paramWrite
+}
+
+//paramWrite is a secret value declared in an environment file,
+//stored in SSM or Secrets Manager by the deploy
+type paramWrite struct {
+	store string
+	value string
+}
+
+//paramMarker is the reference set on the container until
+//the secret is written and its ARN known
+func paramMarker(store, key string) string {
+	return fmt.Sprintf("%s:%s", store, key)
+}
+
+//serviceEnvironment read the environment files of aService,
+//nil when it has none
+func serviceEnvironment(aService *config.Service) (*taskEnvironment, error) {
+	files := make([]string, 0, len(aService.EnvFiles)+1)
+	files = append(files, aService.EnvFiles...)
+	if !strings.EqualFold("", environmentFilePath) {
+		files = append(files, environmentFilePath)
+	}
+
+	merge := strings.EqualFold("merge", aService.EnvMode)
+	switch aService.EnvMode {
+	case "", "replace", "merge":
+	default:
+		return nil, fmt.Errorf("%s: unknown env_mode %s (replace or merge)", aService.Name, aService.EnvMode)
+	}
+	if len(aService.EnvUnset) > 0 && !merge {
+		return nil, fmt.Errorf("%s: env_unset needs env_mode: merge", aService.Name)
+	}
+
+	if len(files) == 0 && len(aService.EnvUnset) == 0 {
+		return nil, nil
+	}
+
+	environment := &taskEnvironment{
+		files:   files,
+		env:     make(map[string]string),
+		secrets: make(map[string]string),
+		unset:   aService.EnvUnset,
+		merge:   merge,
+		writes:  make(map[string]paramWrite),
+	}
+
+	for _, file := range files {
+		log.Printf("Read Env File %s", file)
+		path, err := homedir.Expand(file)
+		if err != nil {
+			return nil, err
+		}
+		values, err := config.ReadTaskEnvFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error while Reading %s: %v", file, err)
+		}
+
+		for k, v := range values {
+			if strings.HasPrefix(k, "_") {
+				name := strings.TrimPrefix(k, "_")
+				delete(environment.env, name)
+				delete(environment.writes, name)
+				environment.secrets[name] = v
+
+				// _KEY=ssm:VALUE or _KEY=secretsmanager:VALUE
+				for _, store := range []string{gtdaws.ParamStoreSSM, gtdaws.ParamStoreSecretsManager} {
+					if strings.HasPrefix(v, fmt.Sprintf("%s:", store)) {
+						environment.writes[name] = paramWrite{store: store, value: strings.TrimPrefix(v, fmt.Sprintf("%s:", store))}
+						environment.secrets[name] = paramMarker(store, name)
+					}
+				}
+			} else {
+				environment.env[k] = v
+				delete(environment.secrets, k)
+				delete(environment.writes, k)
+			}
+		}
+	}
+	return environment, nil
+}
+
+//apply set the environment and secrets of container.
+//In merge mode the variables set outside gtd are kept.
+func (environment *taskEnvironment) apply(container *ecs.ContainerDefinition) {
+	env := make(map[string]string)
+	secrets := make(map[string]string)
+	if environment.merge {
+		env = keyValuePairsToMap(container.Environment)
+		secrets = secretsToMap(container.Secrets)
+	}
+
+	for k, v := range environment.env {
+		env[k] = v
+		delete(secrets, k)
+	}
+	for k, v := range environment.secrets {
+		secrets[k] = v
+		delete(env, k)
+	}
+	for _, k := range environment.unset {
+		delete(env, k)
+		delete(secrets, k)
+	}
+
+	container.SetEnvironment(mapToKeyValuePairs(env))
+	container.SetSecrets(mapToSecrets(secrets))
+}
+
+//writeParams store the secret values declared in the environment files
+//under the parameters path of aService, and Return their ARNs by marker
+func (environment *taskEnvironment) writeParams(cmd *Command, aService *config.Service) (map[string]string, error) {
+	arns := make(map[string]string, len(environment.writes))
+	for _, key := range sortedWriteKeys(environment.writes) {
+		write := environment.writes[key]
+		arn, err := cmd.AWSSession.PutParam(write.store, paramName(write.store, cmd.GTenv, aService.Name, key), write.value)
+		if err != nil {
+			return nil, fmt.Errorf("error while writing %s to %s: %v", key, write.store, err)
+		}
+		arns[paramMarker(write.store, key)] = arn
+	}
+	return arns, nil
+}
+
+//resolveParams replace the secret markers of containers by their ARN
+func resolveParams(containers []*ecs.ContainerDefinition, arns map[string]string) {
+	for _, container := range containers {
+		for _, secret := range container.Secrets {
+			if arn, ok := arns[aws.StringValue(secret.ValueFrom)]; ok {
+				secret.SetValueFrom(arn)
+			}
+		}
+	}
+}
+
+func sortedWriteKeys(writes map[string]paramWrite) []string {
+	keys := make([]string, 0, len(writes))
+	for k := range writes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//mapToKeyValuePairs Return values sorted by name
+func mapToKeyValuePairs(values map[string]string) []*ecs.KeyValuePair {
+	pairs := make([]*ecs.KeyValuePair, 0, len(values))
+	for _, name := range sortedKeys(values) {
+		pairs = append(pairs, &ecs.KeyValuePair{
+			Name:  aws.String(name),
+			Value: aws.String(values[name]),
+		})
+	}
+	return pairs
+}
+
+//mapToSecrets Return values sorted by name
+func mapToSecrets(values map[string]string) []*ecs.Secret {
+	secrets := make([]*ecs.Secret, 0, len(values))
+	for _, name := range sortedKeys(values) {
+		secrets = append(secrets, &ecs.Secret{
+			Name:      aws.String(name),
+			ValueFrom: aws.String(values[name]),
+		})
+	}
+	return secrets
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
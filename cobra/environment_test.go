@@ -0,0 +1,140 @@
+package cobra
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/gpkfr/goretdep/config"
+)
+
+//writeEnvFile write content to a file of the test directory and Return its path
+func writeEnvFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+//testContainer is a container already configured outside gtd
+func testContainer() *ecs.ContainerDefinition {
+	return &ecs.ContainerDefinition{
+		Name: aws.String("app"),
+		Environment: []*ecs.KeyValuePair{
+			{Name: aws.String("MANUAL"), Value: aws.String("kept")},
+			{Name: aws.String("LOG_LEVEL"), Value: aws.String("debug")},
+			{Name: aws.String("OLD"), Value: aws.String("removed")},
+		},
+		Secrets: []*ecs.Secret{
+			{Name: aws.String("MANUAL_SECRET"), ValueFrom: aws.String("arn:manual")},
+			{Name: aws.String("DB_PASSWORD"), ValueFrom: aws.String("arn:old-password")},
+		},
+	}
+}
+
+func TestTaskEnvironmentApply(t *testing.T) {
+	base := writeEnvFile(t, "base.env", "LOG_LEVEL=info\nREGION=eu-west-1\n_DB_PASSWORD=arn:password\nTOKEN=plain\n")
+	override := writeEnvFile(t, "override.env", "LOG_LEVEL=warn\n_TOKEN=arn:token\n")
+	cli := writeEnvFile(t, "cli.env", "REGION=us-east-1\n")
+
+	tests := []struct {
+		name       string
+		service    config.Service
+		configFile string
+		env        map[string]string
+		secrets    map[string]string
+	}{
+		{
+			name:    "replace drops the current environment",
+			service: config.Service{Name: "svc", EnvFiles: []string{base}},
+			env:     map[string]string{"LOG_LEVEL": "info", "REGION": "eu-west-1", "TOKEN": "plain"},
+			secrets: map[string]string{"DB_PASSWORD": "arn:password"},
+		},
+		{
+			name:    "merge keeps the variables set outside gtd",
+			service: config.Service{Name: "svc", EnvFiles: []string{base}, EnvMode: "merge"},
+			env:     map[string]string{"MANUAL": "kept", "LOG_LEVEL": "info", "OLD": "removed", "REGION": "eu-west-1", "TOKEN": "plain"},
+			secrets: map[string]string{"MANUAL_SECRET": "arn:manual", "DB_PASSWORD": "arn:password"},
+		},
+		{
+			name:    "env_unset removes variables and secrets",
+			service: config.Service{Name: "svc", EnvFiles: []string{base}, EnvMode: "merge", EnvUnset: []string{"OLD", "MANUAL_SECRET"}},
+			env:     map[string]string{"MANUAL": "kept", "LOG_LEVEL": "info", "REGION": "eu-west-1", "TOKEN": "plain"},
+			secrets: map[string]string{"DB_PASSWORD": "arn:password"},
+		},
+		{
+			name:    "env_unset alone",
+			service: config.Service{Name: "svc", EnvMode: "merge", EnvUnset: []string{"OLD"}},
+			env:     map[string]string{"MANUAL": "kept", "LOG_LEVEL": "debug"},
+			secrets: map[string]string{"MANUAL_SECRET": "arn:manual", "DB_PASSWORD": "arn:old-password"},
+		},
+		{
+			name:    "the last env file wins, a secret replaces a variable",
+			service: config.Service{Name: "svc", EnvFiles: []string{base, override}},
+			env:     map[string]string{"LOG_LEVEL": "warn", "REGION": "eu-west-1"},
+			secrets: map[string]string{"DB_PASSWORD": "arn:password", "TOKEN": "arn:token"},
+		},
+		{
+			name:       "--config wins over the env files",
+			service:    config.Service{Name: "svc", EnvFiles: []string{base, override}},
+			configFile: cli,
+			env:        map[string]string{"LOG_LEVEL": "warn", "REGION": "us-east-1"},
+			secrets:    map[string]string{"DB_PASSWORD": "arn:password", "TOKEN": "arn:token"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			environmentFilePath = test.configFile
+			defer func() { environmentFilePath = "" }()
+
+			environment, err := serviceEnvironment(&test.service)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if environment == nil {
+				t.Fatal("expected an environment")
+			}
+
+			container := testContainer()
+			environment.apply(container)
+
+			if env := keyValuePairsToMap(container.Environment); !reflect.DeepEqual(env, test.env) {
+				t.Errorf("expected environment %v, got %v", test.env, env)
+			}
+			if secrets := secretsToMap(container.Secrets); !reflect.DeepEqual(secrets, test.secrets) {
+				t.Errorf("expected secrets %v, got %v", test.secrets, secrets)
+			}
+		})
+	}
+}
+
+func TestServiceEnvironmentErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		service config.Service
+	}{
+		{name: "unknown env_mode", service: config.Service{Name: "svc", EnvMode: "append", EnvUnset: []string{"OLD"}}},
+		{name: "env_unset without merge", service: config.Service{Name: "svc", EnvUnset: []string{"OLD"}}},
+		{name: "missing env file", service: config.Service{Name: "svc", EnvFiles: []string{filepath.Join(t.TempDir(), "missing.env")}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := serviceEnvironment(&test.service); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestServiceEnvironmentNone(t *testing.T) {
+	environment, err := serviceEnvironment(&config.Service{Name: "svc"})
+	if err != nil || environment != nil {
+		t.Errorf("expected no environment, got %v (%v)", environment, err)
+	}
+}
@@ -0,0 +1,210 @@
+package cobra
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gpkfr/goretdep/gtdhistory"
+	"github.com/gpkfr/goretdep/gtdlock"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var historySince string
+
+//NewHistoryCommand bind the history command
+func NewHistoryCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the deployments history",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			showHistory(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			// the history is shared by every env, the region comes from the config
+			if strings.EqualFold("", cmd.Services.ECSRegion) {
+				cmd.Services.ECSRegion = viper.GetString("history.region")
+			}
+			cmd.GetAWSSession()
+		},
+	}
+
+	cobraCmd.Flags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to show")
+	cobraCmd.Flags().StringSliceVarP(&cmd.SelectedServices, "service", "s", []string{}, "Service(s) to show. Separated by comma")
+	cobraCmd.Flags().StringVar(&historySince, "since", "", "Only show records newer than [--since 7d, --since 12h]")
+
+	cmd.AddCommand(cobraCmd)
+}
+
+//HistoryStore Return the history store configured in ~/.gtd.yaml
+//(history.backend: file, s3 or none). Default to ~/.gtd/history.jsonl.
+//It is called once services are updated, errors are reported without exiting.
+func (cmd *Command) HistoryStore() gtdhistory.Store {
+	switch viper.GetString("history.backend") {
+	case "none":
+		return nil
+	case "s3":
+		return cmd.AWSSession.NewS3HistoryStore(viper.GetString("history.bucket"), viper.GetString("history.prefix"))
+	default:
+		path := viper.GetString("history.path")
+		if strings.EqualFold("", path) {
+			home, err := homedir.Dir()
+			if err != nil {
+				log.Println(fmt.Errorf("history disabled: %v", err))
+				return nil
+			}
+			path = filepath.Join(home, ".gtd", "history.jsonl")
+		}
+		path, err := homedir.Expand(path)
+		if err != nil {
+			log.Println(fmt.Errorf("history disabled: %v", err))
+			return nil
+		}
+		return &gtdhistory.FileStore{Path: path}
+	}
+}
+
+//RecordHistory append records to the history store.
+//Time, user, env, cluster and reason are filled in.
+//Failing to record is reported but does not stop the command.
+func (cmd *Command) RecordHistory(records ...gtdhistory.Record) {
+	store := cmd.HistoryStore()
+	if store == nil || len(records) == 0 {
+		return
+	}
+
+	user := cmd.whoAmI()
+	now := time.Now().UTC()
+	for i := range records {
+		records[i].Time = now
+		records[i].User = user
+		records[i].Env = cmd.GTenv
+		records[i].Cluster = cmd.Services.ECSCluster
+		records[i].Reason = releaseReason
+	}
+
+	if err := store.Append(records...); err != nil {
+		log.Println(fmt.Errorf("error while recording history: %v", err))
+	}
+}
+
+//whoAmI Return the ARN of the AWS identity in use,
+//or user@host when it cannot be found
+func (cmd *Command) whoAmI() string {
+	user, err := cmd.AWSSession.GetCallerIdentity()
+	if err != nil {
+		return gtdlock.DefaultOwner()
+	}
+	return user
+}
+
+//fileHash Return the sha256 of the content of files, empty when there is no file
+func fileHash(paths ...string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	hash := sha256.New()
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return ""
+		}
+		hash.Write(content)
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil))
+}
+
+//parseDuration parse a positive duration accepting days [7d] on top of time.ParseDuration units
+func parseDuration(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid duration %s", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err == nil && duration < 0 {
+		return 0, fmt.Errorf("invalid duration %s", value)
+	}
+	return duration, err
+}
+
+func showHistory(cmd *Command) {
+	store := cmd.HistoryStore()
+	if store == nil {
+		fmt.Println("History is disabled (history.backend: none)")
+		return
+	}
+
+	filter := gtdhistory.Filter{Env: cmd.GTenv}
+	if !strings.EqualFold("", historySince) {
+		since, err := parseDuration(historySince)
+		if err != nil {
+			log.Fatal(err)
+		}
+		filter.Since = time.Now().Add(-since)
+	}
+
+	records := make([]gtdhistory.Record, 0)
+	if len(cmd.SelectedServices) == 0 {
+		all, err := store.Query(filter)
+		if err != nil {
+			log.Fatal(err)
+		}
+		records = all
+	} else {
+		for _, service := range cmd.SelectedServices {
+			filter.Service = service
+			selected, err := store.Query(filter)
+			if err != nil {
+				log.Fatal(err)
+			}
+			records = append(records, selected...)
+		}
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Date", "User", "Action", "Env", "Service", "Old Revision", "New Revision", "Old Image", "New Image", "Result", "Reason"})
+
+	for _, record := range records {
+		t.AppendRow([]interface{}{
+			record.Time.Local().Format("2006-01-02 15:04:05"),
+			record.User,
+			record.Action,
+			record.Env,
+			record.Service,
+			record.OldTaskDefinition,
+			record.NewTaskDefinition,
+			record.OldImage,
+			record.NewImage,
+			record.Result,
+			record.Reason})
+	}
+
+	switch cmd.TableStyle {
+	case "light":
+		t.SetStyle(table.StyleLight)
+	case "color":
+		t.SetStyle(table.StyleColoredDark)
+	}
+	if t.Length() > cmd.ShowTableIndexAbove {
+		t.SetAutoIndex(true)
+	}
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 8, WidthMax: 30},
+		{Number: 9, WidthMax: 30},
+		{Number: 11, WidthMax: 30},
+	})
+	t.Render()
+}
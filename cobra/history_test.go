@@ -0,0 +1,43 @@
+package cobra
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		duration time.Duration
+		err      bool
+	}{
+		{value: "10m", duration: 10 * time.Minute},
+		{value: "2h", duration: 2 * time.Hour},
+		{value: "1h30m", duration: 90 * time.Minute},
+		{value: "1d", duration: 24 * time.Hour},
+		{value: "7d", duration: 7 * 24 * time.Hour},
+		{value: "0d", duration: 0},
+		{value: "", err: true},
+		{value: "d", err: true},
+		{value: "1.5d", err: true},
+		{value: "-1d", err: true},
+		{value: "-10m", err: true},
+		{value: "week", err: true},
+		{value: "10", err: true},
+	}
+
+	for _, test := range tests {
+		duration, err := parseDuration(test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %s", test.value, duration)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.value, err)
+		} else if duration != test.duration {
+			t.Errorf("%q: expected %s, got %s", test.value, test.duration, duration)
+		}
+	}
+}
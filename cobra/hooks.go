@@ -0,0 +1,69 @@
+package cobra
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	gtdaws "github.com/gpkfr/goretdep/aws"
+	"github.com/gpkfr/goretdep/config"
+)
+
+const defaultHookTimeout = 30 * time.Minute
+
+//runHooks run the hook tasks of aService with taskDefinition, in order,
+//and stop at the first one failing. A row is added to rows for each hook run.
+func runHooks(cmd *Command, rows *deployRows, aService *config.Service, stage string, hooks []config.Hook, taskDefinition string) error {
+	for _, hook := range hooks {
+		name := hook.Name
+		if strings.EqualFold("", name) {
+			name = strings.Join(hook.Command, " ")
+		}
+
+		exitCode, err := runHook(cmd, aService, hook, fmt.Sprintf("[%s %s %s] ", aService.Name, stage, name), taskDefinition)
+		status := "SUCCEEDED"
+		switch {
+		case err != nil:
+			status = fmt.Sprintf("FAILED (%v)", err)
+		case exitCode != 0:
+			err = fmt.Errorf("exit code %d", exitCode)
+			status = fmt.Sprintf("FAILED (exit %d)", exitCode)
+		}
+
+		rows.AppendRow([]interface{}{
+			fmt.Sprintf(" ↳ %s: %s", stage, name),
+			"-",
+			taskDefinition,
+			"-",
+			strings.Join(hook.Command, " "),
+			status,
+			"-"})
+
+		if err != nil {
+			return fmt.Errorf("%s hook %s: %v", stage, name, err)
+		}
+	}
+	return nil
+}
+
+func runHook(cmd *Command, aService *config.Service, hook config.Hook, prefix, taskDefinition string) (int64, error) {
+	timeout := defaultHookTimeout
+	if !strings.EqualFold("", hook.Timeout) {
+		var err error
+		if timeout, err = time.ParseDuration(hook.Timeout); err != nil {
+			return -1, fmt.Errorf("invalid timeout %s", hook.Timeout)
+		}
+	}
+
+	container := hook.Container
+	if strings.EqualFold("", container) {
+		containers, err := gtdaws.SelectContainerDefinitions(aService.TaskDefinition.ContainerDefinitions, serviceContainers(aService)...)
+		if err != nil {
+			return -1, err
+		}
+		container = aws.StringValue(containers[0].Name)
+	}
+
+	return runServiceTask(cmd, aService, taskDefinition, container, hook.Command, nil, prefix, timeout)
+}
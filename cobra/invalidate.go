@@ -1,10 +1,12 @@
 package cobra
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strings"
 
+	"github.com/gpkfr/goretdep/gtdhistory"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +29,7 @@ func NewInvalidateCommand(cmd *Command) {
 	cobraCmd.Flags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
 
 	cobraCmd.Flags().StringSliceVarP(&cmd.SelectedServices, "service", "s", []string{}, "Service(s) to show. Separated by comma")
+	cobraCmd.Flags().StringVar(&releaseReason, "reason", "", "Reason of the invalidation, kept in the history")
 
 	cmd.AddCommand(cobraCmd)
 
@@ -37,6 +40,7 @@ func invalidate(cmd *Command) {
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
 	t.AppendHeader(table.Row{"Cloudfront ID", "Pattern", "Associated Service", "invalidate ID", "Status"})
+	records := make([]gtdhistory.Record, 0)
 
 	if len(cmd.SelectedServices) <= 0 {
 		// Process all (unfiltered) CF invalidations description (even without service associated)
@@ -54,6 +58,7 @@ func invalidate(cmd *Command) {
 					*resp.Invalidation.Id,
 					*resp.Invalidation.Status,
 				})
+				records = append(records, invalidationRecord(cf.AssociatedService, cf.CloudfrontID, cf.CloudFrontPattern, *resp.Invalidation.Id, *resp.Invalidation.Status))
 			}
 		}
 	} else {
@@ -72,11 +77,14 @@ func invalidate(cmd *Command) {
 						*resp.Invalidation.Id,
 						*resp.Invalidation.Status,
 					})
+					records = append(records, invalidationRecord(cf.AssociatedService, cf.CloudfrontID, cf.CloudFrontPattern, *resp.Invalidation.Id, *resp.Invalidation.Status))
 				}
 			}
 		}
 	}
 
+	cmd.RecordHistory(records...)
+
 	switch cmd.TableStyle {
 	case "light":
 		t.SetStyle(table.StyleLight)
@@ -90,3 +98,12 @@ func invalidate(cmd *Command) {
 	t.Render()
 
 }
+
+//invalidationRecord build the audit record of a CloudFront invalidation
+func invalidationRecord(service, cloudfrontID, pattern, invalidationID, status string) gtdhistory.Record {
+	return gtdhistory.Record{
+		Action:  "invalidate",
+		Service: service,
+		Result:  fmt.Sprintf("%s %s %s %s", cloudfrontID, pattern, invalidationID, status),
+	}
+}
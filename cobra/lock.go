@@ -0,0 +1,223 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gpkfr/goretdep/config"
+	"github.com/gpkfr/goretdep/gtdlock"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	releaseReason    string
+	lockForceRelease bool
+)
+
+//NewLockCommand bind the lock command and its subcommands
+func NewLockCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Show or release the deploy lock of an environment",
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show who holds the deploy lock",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			lockStatus(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+	statusCmd.Flags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
+
+	releaseCmd := &cobra.Command{
+		Use:   "release",
+		Short: "Release the deploy lock",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			lockRelease(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+	releaseCmd.Flags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
+	releaseCmd.Flags().BoolVar(&lockForceRelease, "force", false, "Release a lock held by someone else")
+
+	cobraCmd.AddCommand(statusCmd, releaseCmd)
+	cmd.AddCommand(cobraCmd)
+}
+
+//LockBackend Return the lock backend configured in ~/.gtd.yaml
+//(lock.backend: file, dynamodb, s3 or none). Default to files in ~/.gtd/locks.
+func (cmd *Command) LockBackend() gtdlock.Backend {
+	switch viper.GetString("lock.backend") {
+	case "none":
+		return nil
+	case "dynamodb":
+		return cmd.AWSSession.NewDynamoDBLockBackend(viper.GetString("lock.table"))
+	case "s3":
+		return cmd.AWSSession.NewS3LockBackend(viper.GetString("lock.bucket"), viper.GetString("lock.prefix"))
+	default:
+		dir := viper.GetString("lock.dir")
+		if strings.EqualFold("", dir) {
+			home, err := homedir.Dir()
+			if err != nil {
+				log.Fatal(err)
+			}
+			dir = filepath.Join(home, ".gtd", "locks")
+		}
+		return &gtdlock.FileBackend{Dir: dir}
+	}
+}
+
+//AcquireLock lock the environment (and its ECS cluster) for command.
+//It exits when someone else holds the lock and returns the release function.
+//The lock is refreshed until released, a deploy waiting longer than the ttl keeps it.
+func (cmd *Command) AcquireLock(command string) func() {
+	backend := cmd.LockBackend()
+	if backend == nil {
+		return func() {}
+	}
+
+	ttl := viper.GetDuration("lock.ttl")
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+
+	lock := gtdlock.New(gtdlock.Key(cmd.GTenv, cmd.Services.ECSCluster), gtdlock.DefaultOwner(), releaseReason, command, ttl)
+	if err := backend.Acquire(lock); err != nil {
+		log.Fatal(fmt.Errorf("(🔒) %v", err))
+	}
+
+	done := make(chan struct{})
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				lock.ExpiresAt = time.Now().UTC().Add(ttl)
+				if err := backend.Refresh(lock); err != nil {
+					log.Println(fmt.Errorf("error while refreshing lock %s: %v", lock.Key, err))
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// no refresh may run after the release
+			close(done)
+			<-refreshed
+			if err := backend.Release(lock.Key, lock.Token, false); err != nil {
+				log.Println(fmt.Errorf("error while releasing lock %s: %v", lock.Key, err))
+			}
+		})
+	}
+}
+
+func loadLockKey(cmd *Command) string {
+	if err := config.LoadService(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, &cmd.GTenv); err != nil {
+		log.Fatal(err)
+	}
+	return gtdlock.Key(cmd.GTenv, cmd.Services.ECSCluster)
+}
+
+func lockStatus(cmd *Command) {
+	key := loadLockKey(cmd)
+	backend := cmd.LockBackend()
+	if backend == nil {
+		fmt.Println("Locking is disabled (lock.backend: none)")
+		return
+	}
+
+	lock, err := backend.Get(key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if lock == nil {
+		fmt.Printf("(🔓) %s is not locked\n", key)
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Key", "Owner", "Command", "Reason", "Since", "Expires", "status"})
+
+	status := "LOCKED"
+	if lock.Expired() {
+		status = "EXPIRED"
+	}
+	t.AppendRow([]interface{}{
+		lock.Key,
+		lock.Owner,
+		lock.Command,
+		lock.Reason,
+		lock.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+		lock.ExpiresAt.Local().Format("2006-01-02 15:04:05"),
+		status})
+
+	switch cmd.TableStyle {
+	case "light":
+		t.SetStyle(table.StyleLight)
+	case "color":
+		t.SetStyle(table.StyleColoredDark)
+	}
+	t.Render()
+}
+
+func lockRelease(cmd *Command) {
+	key := loadLockKey(cmd)
+	backend := cmd.LockBackend()
+	if backend == nil {
+		fmt.Println("Locking is disabled (lock.backend: none)")
+		return
+	}
+
+	lock, err := backend.Get(key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if lock == nil {
+		fmt.Printf("(🔓) %s is not locked\n", key)
+		return
+	}
+
+	if lockForceRelease {
+		if err := backend.Release(key, "", true); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("(🔓) %s released\n", key)
+		return
+	}
+
+	// only the command holding the lock knows its token, an expired lock can be released.
+	// The release is refused if the lock was taken again since it was read.
+	if !lock.Expired() {
+		log.Fatal(fmt.Errorf("%v\nUse `--force` to release it anyway", &gtdlock.LockedError{Lock: lock}))
+	}
+	if err := backend.Release(key, lock.Token, false); err != nil {
+		log.Fatal(fmt.Errorf("%v\nUse `--force` to release it anyway", err))
+	}
+	fmt.Printf("(🔓) %s released\n", key)
+}
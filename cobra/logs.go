@@ -0,0 +1,159 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	gtdaws "github.com/gpkfr/goretdep/aws"
+	"github.com/gpkfr/goretdep/config"
+	"github.com/spf13/cobra"
+)
+
+const logsPollInterval = 5 * time.Second
+
+//logsFollowLookback is how far back each --follow poll reads again,
+//events may reach CloudWatch Logs after newer ones
+const logsFollowLookback = 2 * time.Minute
+
+var (
+	logsService   string
+	logsContainer string
+	logsSince     string
+	logsFilter    string
+	logsFollow    bool
+)
+
+//NewLogsCommand bind the logs command
+func NewLogsCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "logs -s service",
+		Short: "Print the CloudWatch logs of the running tasks of a service",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			showLogs(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+
+	cobraCmd.Flags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
+	cobraCmd.Flags().StringVarP(&logsService, "service", "s", "", "Service whose logs are printed")
+	cobraCmd.Flags().StringVar(&logsContainer, "container", "", "Container whose logs are printed (default: stack file 'container' or first container)")
+	cobraCmd.Flags().StringVar(&logsSince, "since", "10m", "Print the events more recent than [--since 10m|2h|1d]")
+	cobraCmd.Flags().StringVar(&logsFilter, "filter", "", "CloudWatch Logs filter pattern [--filter ERROR]")
+	cobraCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new events")
+	if err := cobraCmd.MarkFlagRequired("service"); err != nil {
+		fmt.Printf("logs.missing.service err:%v\n", err)
+	}
+
+	cmd.AddCommand(cobraCmd)
+}
+
+func showLogs(cmd *Command) {
+	cmd.AWSSession.GetServices(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, cmd.GTenv, false, logsService)
+
+	var aService *config.Service
+	for i := range cmd.Services.Services {
+		if strings.EqualFold(logsService, cmd.Services.Services[i].Name) && cmd.Services.Services[i].TaskDefinition != nil {
+			aService = &cmd.Services.Services[i]
+		}
+	}
+	if aService == nil {
+		log.Fatalf("service %s not found in %s", logsService, cmd.GTenv)
+	}
+
+	names := serviceContainers(aService)
+	if !strings.EqualFold("", logsContainer) {
+		names = []string{logsContainer}
+	}
+	containers, err := gtdaws.SelectContainerDefinitions(aService.TaskDefinition.ContainerDefinitions, names...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	stream, err := gtdaws.ContainerLogStream(containers[0], "")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	since, err := parseDuration(logsSince)
+	if err != nil {
+		log.Fatal(err)
+	}
+	start := time.Now().Add(-since).UnixNano() / int64(time.Millisecond)
+
+	// after the first fetch, each poll reads the lookback window again,
+	// the events printed are kept by id
+	seen := make(map[string]int64)
+	for {
+		poll := time.Now()
+
+		// tasks are listed on each poll, a deploy replaces them
+		taskArns, err := cmd.AWSSession.ListServiceTasks(cmd.AWSSession.Svc, cmd.Services.ECSCluster, aService.Name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		streams := make([]string, 0, len(taskArns))
+		for _, taskArn := range taskArns {
+			streams = append(streams, stream.Prefix+gtdaws.TaskID(taskArn))
+		}
+
+		if len(streams) == 0 && !logsFollow {
+			fmt.Printf("No running task for %s\n", aService.Name)
+			return
+		}
+
+		if len(streams) > 0 {
+			events, err := cmd.AWSSession.FilterLogEvents(stream.Group, stream.Region, stream.Prefix, streams, start, logsFilter)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, event := range events {
+				if _, found := seen[aws.StringValue(event.EventId)]; found {
+					continue
+				}
+				seen[aws.StringValue(event.EventId)] = aws.Int64Value(event.Timestamp)
+
+				fmt.Printf("[%s] %s %s\n",
+					shortTaskID(strings.TrimPrefix(aws.StringValue(event.LogStreamName), stream.Prefix)),
+					time.Unix(0, aws.Int64Value(event.Timestamp)*int64(time.Millisecond)).Format("2006-01-02 15:04:05"),
+					strings.TrimRight(aws.StringValue(event.Message), "\n"))
+			}
+		}
+
+		if !logsFollow {
+			return
+		}
+
+		start = followStart(start, poll)
+		for id, timestamp := range seen {
+			if timestamp < start {
+				delete(seen, id)
+			}
+		}
+		time.Sleep(logsPollInterval)
+	}
+}
+
+//followStart Return the start (in ms) of the poll following the one made at poll:
+//the lookback window before poll, never before the previous start
+func followStart(start int64, poll time.Time) int64 {
+	lookback := poll.Add(-logsFollowLookback).UnixNano() / int64(time.Millisecond)
+	if lookback > start {
+		return lookback
+	}
+	return start
+}
+
+//shortTaskID Return the first characters of a task id, enough to tell the tasks of a service apart
+func shortTaskID(taskID string) string {
+	if len(taskID) > 8 {
+		return taskID[:8]
+	}
+	return taskID
+}
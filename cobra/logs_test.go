@@ -0,0 +1,30 @@
+package cobra
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFollowStart(t *testing.T) {
+	poll := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	ms := func(t time.Time) int64 {
+		return t.UnixNano() / int64(time.Millisecond)
+	}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		expected time.Time
+	}{
+		{name: "--since 10m moves to the lookback window", start: poll.Add(-10 * time.Minute), expected: poll.Add(-logsFollowLookback)},
+		{name: "--since 1d moves to the lookback window", start: poll.Add(-24 * time.Hour), expected: poll.Add(-logsFollowLookback)},
+		{name: "--since shorter than the lookback is kept", start: poll.Add(-time.Minute), expected: poll.Add(-time.Minute)},
+		{name: "never moves back", start: poll, expected: poll},
+	}
+
+	for _, test := range tests {
+		if start := followStart(ms(test.start), poll); start != ms(test.expected) {
+			t.Errorf("%s: expected %d, got %d", test.name, ms(test.expected), start)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package cobra
+
+import (
+	"log"
+	"time"
+
+	"github.com/gpkfr/goretdep/gtdnotify"
+	"github.com/spf13/viper"
+)
+
+//Notifier Return a notifier of the sinks configured in ~/.gtd.yaml
+//and in the stack file (notifications:), nil when there is none.
+func (cmd *Command) Notifier() *gtdnotify.Notifier {
+	sinks := make([]gtdnotify.Sink, 0)
+
+	var notifications []struct {
+		Type   string   `mapstructure:"type"`
+		URL    string   `mapstructure:"url"`
+		Events []string `mapstructure:"events"`
+	}
+	if err := viper.UnmarshalKey("notifications", &notifications); err != nil {
+		log.Printf("invalid notifications in config file: %v", err)
+	}
+	for _, notification := range notifications {
+		sinks = append(sinks, gtdnotify.Sink{Type: notification.Type, URL: notification.URL, Events: notification.Events})
+	}
+
+	for _, notification := range cmd.Services.Notifications {
+		sinks = append(sinks, gtdnotify.Sink{Type: notification.Type, URL: notification.URL, Events: notification.Events})
+	}
+
+	if len(sinks) == 0 {
+		return nil
+	}
+	return gtdnotify.NewNotifier(sinks)
+}
+
+//Notify send an event of kind to the notification sinks.
+//Failing to notify is reported but does not stop the command.
+func (cmd *Command) Notify(action, kind string, changes []gtdnotify.Change) {
+	notifier := cmd.Notifier()
+	if notifier == nil {
+		return
+	}
+
+	event := gtdnotify.Event{
+		Kind:    kind,
+		Action:  action,
+		Env:     cmd.GTenv,
+		Cluster: cmd.Services.ECSCluster,
+		User:    cmd.whoAmI(),
+		Reason:  releaseReason,
+		Time:    time.Now().UTC(),
+		Changes: changes,
+	}
+	if err := notifier.Notify(event); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,235 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	gtdaws "github.com/gpkfr/goretdep/aws"
+	"github.com/gpkfr/goretdep/config"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	paramsService        string
+	paramsSecretsManager bool
+	paramsShowValues     bool
+	paramsAgainst        string
+)
+
+//NewParamsCommand bind the params command and its subcommands
+func NewParamsCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "params",
+		Short: "Manage the SSM parameters and Secrets Manager secrets of a service",
+		PersistentPreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+			if err := config.LoadService(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, &cmd.GTenv); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	cobraCmd.PersistentFlags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
+	cobraCmd.PersistentFlags().StringVarP(&paramsService, "service", "s", "", "Service owning the parameters")
+	if err := cobraCmd.MarkPersistentFlagRequired("service"); err != nil {
+		fmt.Printf("params.missing.service err:%v\n", err)
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the parameters of a service",
+		Args:  cobra.NoArgs,
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			paramsList(cmd)
+		},
+	}
+	listCmd.Flags().BoolVar(&paramsShowValues, "values", false, "Show the decrypted values")
+
+	getCmd := &cobra.Command{
+		Use:   "get KEY",
+		Short: "Show the value of a parameter",
+		Args:  cobra.ExactArgs(1),
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			paramsGet(cmd, args[0])
+		},
+	}
+	getCmd.Flags().BoolVar(&paramsSecretsManager, "secretsmanager", false, "Use Secrets Manager instead of SSM Parameter Store")
+
+	setCmd := &cobra.Command{
+		Use:   "set KEY VALUE",
+		Short: "Create or update a parameter (SSM SecureString or secret)",
+		Args:  cobra.ExactArgs(2),
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			paramsSet(cmd, args[0], args[1])
+		},
+	}
+	setCmd.Flags().BoolVar(&paramsSecretsManager, "secretsmanager", false, "Use Secrets Manager instead of SSM Parameter Store")
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete KEY",
+		Short: "Delete a parameter",
+		Args:  cobra.ExactArgs(1),
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			paramsDelete(cmd, args[0])
+		},
+	}
+	deleteCmd.Flags().BoolVar(&paramsSecretsManager, "secretsmanager", false, "Use Secrets Manager instead of SSM Parameter Store")
+	deleteCmd.Flags().BoolVar(&assumeYes, "yes", false, "Delete without confirmation")
+
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare the parameters of a service with another environment",
+		Args:  cobra.NoArgs,
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			paramsDiff(cmd)
+		},
+	}
+	diffCmd.Flags().StringVar(&paramsAgainst, "against", "", "Environment to compare with")
+	if err := diffCmd.MarkFlagRequired("against"); err != nil {
+		fmt.Printf("params.diff.missing.against err:%v\n", err)
+	}
+
+	cobraCmd.AddCommand(listCmd, getCmd, setCmd, deleteCmd, diffCmd)
+	cmd.AddCommand(cobraCmd)
+}
+
+//paramsPath Return the path of the parameters of a service: /gt/<env>/<service>/
+//The prefix is params.prefix in ~/.gtd.yaml.
+func paramsPath(env, service string) string {
+	prefix := viper.GetString("params.prefix")
+	if strings.EqualFold("", prefix) {
+		prefix = "/gt"
+	}
+	return fmt.Sprintf("/%s/%s/%s/", strings.Trim(prefix, "/"), env, service)
+}
+
+//paramName Return the name of KEY in store,
+//secrets names do not start with a slash
+func paramName(store, env, service, key string) string {
+	name := fmt.Sprintf("%s%s", paramsPath(env, service), key)
+	if store == gtdaws.ParamStoreSecretsManager {
+		return strings.TrimPrefix(name, "/")
+	}
+	return name
+}
+
+func paramsStore() string {
+	if paramsSecretsManager {
+		return gtdaws.ParamStoreSecretsManager
+	}
+	return gtdaws.ParamStoreSSM
+}
+
+func paramsList(cmd *Command) {
+	params, err := cmd.AWSSession.ListParams(paramsPath(cmd.GTenv, paramsService), paramsShowValues)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	header := table.Row{"Key", "Store", "Version", "ARN"}
+	if paramsShowValues {
+		header = append(header, "Value")
+	}
+	t.AppendHeader(header)
+
+	path := paramsPath(cmd.GTenv, paramsService)
+	for _, param := range params {
+		row := table.Row{
+			strings.TrimPrefix(strings.TrimPrefix(param.Name, path), strings.TrimPrefix(path, "/")),
+			param.Store,
+			param.Version,
+			param.ARN}
+		if paramsShowValues {
+			row = append(row, param.Value)
+		}
+		t.AppendRow(row)
+	}
+
+	switch cmd.TableStyle {
+	case "light":
+		t.SetStyle(table.StyleLight)
+	case "color":
+		t.SetStyle(table.StyleColoredDark)
+	}
+	if t.Length() > cmd.ShowTableIndexAbove {
+		t.SetAutoIndex(true)
+	}
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 5, WidthMax: 40},
+	})
+	t.Render()
+}
+
+func paramsGet(cmd *Command, key string) {
+	param, err := cmd.AWSSession.GetParam(paramsStore(), paramName(paramsStore(), cmd.GTenv, paramsService, key))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(param.Value)
+}
+
+func paramsSet(cmd *Command, key, value string) {
+	name := paramName(paramsStore(), cmd.GTenv, paramsService, key)
+	arn, err := cmd.AWSSession.PutParam(paramsStore(), name, value)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s set\n_%s=%s\n", name, key, arn)
+}
+
+func paramsDelete(cmd *Command, key string) {
+	name := paramName(paramsStore(), cmd.GTenv, paramsService, key)
+	if !assumeYes && !confirm(fmt.Sprintf("Delete %s?", name)) {
+		return
+	}
+	if err := cmd.AWSSession.DeleteParam(paramsStore(), name); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s deleted\n", name)
+}
+
+//paramsDiff compare the keys and values of the parameters of two envs, values are masked
+func paramsDiff(cmd *Command) {
+	current, err := paramValues(cmd, cmd.GTenv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	against, err := paramValues(cmd, paramsAgainst)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	changes := diffValues(against, current, true)
+	if len(changes) == 0 {
+		fmt.Printf("%s has the same parameters in %s and %s\n", paramsService, paramsAgainst, cmd.GTenv)
+		return
+	}
+	printChanges("", fmt.Sprintf("%s: %s -> %s", paramsService, paramsAgainst, cmd.GTenv), changes)
+}
+
+//paramValues Return the values of the parameters of the service in env, by key
+func paramValues(cmd *Command, env string) (map[string]string, error) {
+	path := paramsPath(env, paramsService)
+	params, err := cmd.AWSSession.ListParams(path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(params))
+	for _, param := range params {
+		key := strings.TrimPrefix(strings.TrimPrefix(param.Name, path), strings.TrimPrefix(path, "/"))
+		values[fmt.Sprintf("%s (%s)", key, param.Store)] = param.Value
+	}
+	return values, nil
+}
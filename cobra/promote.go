@@ -0,0 +1,212 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	gtdaws "github.com/gpkfr/goretdep/aws"
+	"github.com/gpkfr/goretdep/config"
+	"github.com/gpkfr/goretdep/gtddocker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteFrom          string
+	promoteMap           []string
+	promoteRewriteSuffix string
+)
+
+//NewPromoteCommand bind the promote command
+func NewPromoteCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Deploy the images running in an environment to another one",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			promote(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+
+	cobraCmd.Flags().StringVar(&promoteFrom, "from", "", "Environment to promote from")
+	cobraCmd.Flags().StringVar(&cmd.GTenv, "to", "", "Environment to deploy")
+	cobraCmd.Flags().StringSliceVarP(&cmd.SelectedServices, "service", "s", []string{}, "Service(s) of the source environment to promote. Separated by comma")
+	cobraCmd.Flags().StringSliceVar(&promoteMap, "map", []string{}, "Source to target service mapping [--map svc-recette-hapi=svc-prod-hapi]. Default: services with the same registry")
+	cobraCmd.Flags().StringVar(&promoteRewriteSuffix, "rewrite-suffix", "", "Rewrite the suffix of the image tags [--rewrite-suffix -rct:-prd]")
+	cobraCmd.Flags().BoolVar(&assumeYes, "yes", false, "Deploy without confirmation")
+	cobraCmd.Flags().StringVar(&releaseReason, "reason", "", "Reason of the deploy, shown to whoever finds the environment locked")
+	cobraCmd.Flags().BoolVar(&waitDeploy, "wait", false, "Wait for updated services to reach a steady state")
+	cobraCmd.Flags().DurationVar(&waitTimeout, "timeout", 15*time.Minute, "Maximum time to wait for services to stabilize [--timeout 15m]")
+	cobraCmd.Flags().IntVar(&deployParallel, "parallel", 1, "Number of services deployed at once")
+	cobraCmd.Flags().BoolVar(&autoRollback, "auto-rollback", false, "Rollback services that fail to stabilize to their previous revision (implies --wait)")
+	cobraCmd.Flags().BoolVar(&pinDigest, "pin-digest", false, "Deploy the image by digest (repo:tag@sha256:...) so its tag cannot change under the service")
+	cobraCmd.Flags().BoolVar(&skipImageCheck, "skip-image-check", false, "Do not check that the image exists in its registry before deploying")
+	for _, name := range []string{"from", "to"} {
+		if err := cobraCmd.MarkFlagRequired(name); err != nil {
+			fmt.Printf("promote.missing.%s err:%v\n", name, err)
+		}
+	}
+
+	cmd.AddCommand(cobraCmd)
+}
+
+func promote(cmd *Command) {
+	if strings.EqualFold(promoteFrom, cmd.GTenv) {
+		log.Fatal("--from and --to are the same environment")
+	}
+
+	rewriteFrom, rewriteTo, err := parseRewriteSuffix(promoteRewriteSuffix)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// service names are matched case insensitively, keys are lower case
+	mapping := make(map[string]string)
+	for _, m := range promoteMap {
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 || strings.EqualFold("", parts[0]) || strings.EqualFold("", parts[1]) {
+			log.Fatalf("invalid mapping %s, expected source=target", m)
+		}
+		if sourceName, found := mapping[strings.ToLower(parts[1])]; found {
+			log.Fatalf("invalid mapping %s, %s is already mapped from %s", m, parts[1], sourceName)
+		}
+		mapping[strings.ToLower(parts[1])] = strings.ToLower(parts[0])
+	}
+
+	// images running in the source env
+	var source config.Services
+	var sourceRepositories config.Repositories
+	var sourceChildTasks config.ChildTasks
+	sourceSession, err := gtdaws.NewAWSSession(&source.ECSRegion, &cmd.AWSProfile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sourceSession.GetServices(&source, &sourceRepositories, &sourceChildTasks, promoteFrom, false, cmd.SelectedServices...)
+
+	sourceImages := make(map[string]string)
+	for _, aService := range source.Services {
+		if aService.TaskDefinition == nil {
+			continue
+		}
+		containers, err := gtdaws.SelectContainerDefinitions(aService.TaskDefinition.ContainerDefinitions, serviceContainers(&aService)...)
+		if err != nil {
+			log.Fatalf("%s (%s): %v", aService.Name, promoteFrom, err)
+		}
+		sourceImages[strings.ToLower(aService.Name)] = aws.StringValue(containers[0].Image)
+	}
+
+	// map them to the target services
+	var target config.Services
+	if err := config.LoadService(&target, &config.Repositories{}, &config.ChildTasks{}, &cmd.GTenv); err != nil {
+		log.Fatal(err)
+	}
+
+	images := make(map[string]string)
+	mapped := make(map[string]bool)
+	for _, aService := range target.Services {
+		sourceName, isMapped := mapping[strings.ToLower(aService.Name)]
+		if isMapped {
+			mapped[strings.ToLower(aService.Name)] = true
+		}
+		if aService.IgnoreDeploy {
+			continue
+		}
+
+		candidates := make(map[string]bool)
+		if isMapped {
+			image, found := sourceImages[sourceName]
+			if !found {
+				log.Fatalf("%s: service %s not found in %s", aService.Name, sourceName, promoteFrom)
+			}
+			candidates[image] = true
+		} else if len(promoteMap) == 0 && !strings.EqualFold("", aService.Registry) {
+			// services without a registry cannot be matched to anything
+			for _, sourceService := range source.Services {
+				if image, found := sourceImages[strings.ToLower(sourceService.Name)]; found && strings.EqualFold(sourceService.Registry, aService.Registry) {
+					candidates[image] = true
+				}
+			}
+		}
+
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			for image := range candidates {
+				images[aService.Name] = rewriteTagSuffix(image, rewriteFrom, rewriteTo)
+			}
+		default:
+			found := make([]string, 0, len(candidates))
+			for image := range candidates {
+				found = append(found, image)
+			}
+			sort.Strings(found)
+			log.Fatalf("%s: several images run in %s for %s (%s), use --map", aService.Name, promoteFrom, aService.Registry, strings.Join(found, ", "))
+		}
+	}
+
+	for targetName := range mapping {
+		if !mapped[targetName] {
+			log.Fatalf("invalid mapping: service %s not found in %s", targetName, cmd.GTenv)
+		}
+	}
+
+	if len(images) == 0 {
+		fmt.Printf("Nothing to promote from %s to %s\n", promoteFrom, cmd.GTenv)
+		os.Exit(0)
+	}
+
+	selected := make([]string, 0, len(images))
+	for name := range images {
+		selected = append(selected, name)
+	}
+	cmd.SelectedServices = selected
+
+	cmd.AWSSession.GetServices(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, cmd.GTenv, true, cmd.SelectedServices...)
+	for i := range cmd.Services.Services {
+		cmd.Services.Services[i].DesiredImage = images[cmd.Services.Services[i].Name]
+	}
+
+	fmt.Printf("Promote %s -> %s\n\n", promoteFrom, cmd.GTenv)
+	planServices(cmd)
+
+	if !assumeYes && !confirm(fmt.Sprintf("Deploy these changes to %s?", cmd.GTenv)) {
+		fmt.Println("Quitting Now, bye (🐷)")
+		os.Exit(0)
+	}
+
+	applyDeploy(cmd)
+}
+
+//parseRewriteSuffix split a -rct:-prd suffix rewrite
+func parseRewriteSuffix(rewrite string) (string, string, error) {
+	if strings.EqualFold("", rewrite) {
+		return "", "", nil
+	}
+	parts := strings.SplitN(rewrite, ":", 2)
+	if len(parts) != 2 || strings.EqualFold("", parts[0]) {
+		return "", "", fmt.Errorf("invalid suffix rewrite %s, expected -from:-to", rewrite)
+	}
+	return parts[0], parts[1], nil
+}
+
+//rewriteTagSuffix replace the suffix from of the image tag by to.
+//The digest is dropped since the rewritten tag points to another image.
+func rewriteTagSuffix(image, from, to string) string {
+	if strings.EqualFold("", from) {
+		return image
+	}
+	tagged := gtddocker.Tagged(image)
+	if !strings.HasSuffix(tagged, from) {
+		return image
+	}
+	return fmt.Sprintf("%s%s", strings.TrimSuffix(tagged, from), to)
+}
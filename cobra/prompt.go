@@ -0,0 +1,20 @@
+package cobra
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//confirm ask question on the terminal, only y/yes is accepted
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,232 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	gtdaws "github.com/gpkfr/goretdep/aws"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneKeep      int
+	pruneOlderThan string
+)
+
+//familyPrune hold the revisions of a family to deregister
+type familyPrune struct {
+	family string
+	active int
+	inUse  int
+	arns   []string
+}
+
+//NewPruneRevisionsCommand bind the prune-revisions command
+func NewPruneRevisionsCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "prune-revisions",
+		Short: "Deregister old task definition revisions not in use",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			pruneRevisions(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+
+	cobraCmd.Flags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
+	cobraCmd.Flags().StringSliceVarP(&cmd.SelectedServices, "service", "s", []string{}, "Service(s) to prune. Separated by comma")
+	cobraCmd.Flags().IntVar(&pruneKeep, "keep", 20, "Number of revisions kept per family")
+	cobraCmd.Flags().StringVar(&pruneOlderThan, "older-than", "", "Only deregister revisions registered before [--older-than 90d]")
+	cobraCmd.Flags().BoolVar(&assumeYes, "yes", false, "Deregister without confirmation")
+	cobraCmd.Flags().StringVar(&releaseReason, "reason", "", "Reason of the prune, shown to whoever finds the environment locked")
+
+	cmd.AddCommand(cobraCmd)
+}
+
+func pruneRevisions(cmd *Command) {
+	if pruneKeep < 1 {
+		log.Fatal("--keep must be at least 1")
+	}
+	var olderThan time.Time
+	if !strings.EqualFold("", pruneOlderThan) {
+		age, err := parseDuration(pruneOlderThan)
+		if err != nil {
+			log.Fatal(err)
+		}
+		olderThan = time.Now().Add(-age)
+	}
+
+	cmd.AWSSession.GetServices(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, cmd.GTenv, false, cmd.SelectedServices...)
+
+	families := make([]string, 0)
+	for _, aService := range cmd.Services.Services {
+		if aService.TaskDefinition == nil {
+			continue
+		}
+		families = append(families, aws.StringValue(aService.TaskDefinition.Family))
+		for _, t := range cmd.ChildTasks.ChildTasks {
+			if strings.EqualFold(t.ParentService, aService.Name) {
+				families = append(families, gtdaws.TaskDefinitionFamily(t.Name))
+			}
+		}
+	}
+
+	inUse, err := cmd.AWSSession.TaskDefinitionsInUse(cmd.AWSSession.Svc, cmd.Services.ECSCluster)
+	if err != nil {
+		log.Fatal(fmt.Errorf("error while listing the task definitions in use\n%s", err.Error()))
+	}
+	scheduled, err := cmd.AWSSession.ScheduledTaskDefinitions()
+	if err != nil {
+		log.Fatal(fmt.Errorf("error while listing the scheduled tasks\n%s", err.Error()))
+	}
+	for arn := range scheduled {
+		inUse[arn] = true
+	}
+
+	prunes := make([]*familyPrune, 0, len(families))
+	seen := make(map[string]bool)
+	for _, family := range families {
+		if seen[family] {
+			continue
+		}
+		seen[family] = true
+
+		prune, err := planFamilyPrune(cmd, family, inUse, olderThan)
+		if err != nil {
+			log.Fatal(err)
+		}
+		prunes = append(prunes, prune)
+	}
+
+	var total int
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Family", "Active revisions", "In use", "To deregister", "Revisions"})
+	for _, prune := range prunes {
+		total += len(prune.arns)
+		t.AppendRow([]interface{}{prune.family, prune.active, prune.inUse, len(prune.arns), revisionRange(prune.arns)})
+	}
+	renderPruneTable(cmd, t)
+
+	if total == 0 {
+		fmt.Println("Nothing to deregister")
+		return
+	}
+	if !assumeYes && !confirm(fmt.Sprintf("Deregister %d revisions?", total)) {
+		fmt.Println("Quitting Now, bye (🐷)")
+		return
+	}
+
+	release := cmd.AcquireLock("prune-revisions")
+	defer release()
+
+	var failed bool
+	summary := table.NewWriter()
+	summary.SetOutputMirror(os.Stdout)
+	summary.AppendHeader(table.Row{"Family", "Deregistered", "Failed", "status"})
+	for _, prune := range prunes {
+		if len(prune.arns) == 0 {
+			continue
+		}
+		deregistered := make([]string, 0, len(prune.arns))
+		failures := make([]string, 0)
+		for _, arn := range prune.arns {
+			taskDefinition := arn
+			if err := cmd.AWSSession.DeregisterAWSTaskDefinition(cmd.AWSSession.Svc, &taskDefinition); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", revisionOf(arn), err))
+				continue
+			}
+			deregistered = append(deregistered, arn)
+		}
+
+		status := "DEREGISTERED"
+		if len(failures) > 0 {
+			failed = true
+			status = strings.Join(failures, "\n")
+		}
+		summary.AppendRow([]interface{}{prune.family, revisionRange(deregistered), len(failures), status})
+	}
+	renderPruneTable(cmd, summary)
+
+	if failed {
+		release()
+		os.Exit(1)
+	}
+}
+
+//planFamilyPrune list the revisions of family to deregister: the ACTIVE revisions
+//after the pruneKeep newest ones, not in use and registered before olderThan
+func planFamilyPrune(cmd *Command, family string, inUse map[string]bool, olderThan time.Time) (*familyPrune, error) {
+	arns, err := cmd.AWSSession.ListTaskDefinitionArns(cmd.AWSSession.Svc, family, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing revisions of %s\n%s", family, err.Error())
+	}
+
+	prune := &familyPrune{family: family, active: len(arns), arns: make([]string, 0)}
+	for i, arn := range arns {
+		used := inUse[arn] || inUse[fmt.Sprintf("%s:%s", family, revisionOf(arn))]
+		// a family without revision means its latest ACTIVE revision
+		if i == 0 && (inUse[family] || inUse[strings.TrimSuffix(arn, fmt.Sprintf(":%s", revisionOf(arn)))]) {
+			used = true
+		}
+		if used {
+			prune.inUse++
+		}
+		if i < pruneKeep || used {
+			continue
+		}
+
+		if !olderThan.IsZero() {
+			taskDefinition, err := cmd.AWSSession.GetCurrentTaskDefinition(cmd.AWSSession.Svc, arn)
+			if err != nil {
+				return nil, err
+			}
+			if taskDefinition.TaskDefinition.RegisteredAt != nil && taskDefinition.TaskDefinition.RegisteredAt.After(olderThan) {
+				continue
+			}
+		}
+		prune.arns = append(prune.arns, arn)
+	}
+	return prune, nil
+}
+
+//revisionOf Return the revision of a task definition ARN
+func revisionOf(arn string) string {
+	if i := strings.LastIndex(arn, ":"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn
+}
+
+//revisionRange summarize revisions (newest first) as oldest..newest
+func revisionRange(arns []string) string {
+	switch len(arns) {
+	case 0:
+		return "-"
+	case 1:
+		return revisionOf(arns[0])
+	default:
+		return fmt.Sprintf("%s..%s (%d)", revisionOf(arns[len(arns)-1]), revisionOf(arns[0]), len(arns))
+	}
+}
+
+func renderPruneTable(cmd *Command, t table.Writer) {
+	switch cmd.TableStyle {
+	case "light":
+		t.SetStyle(table.StyleLight)
+	case "color":
+		t.SetStyle(table.StyleColoredDark)
+	}
+	if t.Length() > cmd.ShowTableIndexAbove {
+		t.SetAutoIndex(true)
+	}
+	t.Render()
+}
@@ -0,0 +1,261 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/gpkfr/goretdep/gtdhistory"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rollbackRevision int64
+	rollbackListOnly bool
+	rollbackHistory  int64
+)
+
+//NewRollbackCommand bind the rollback command
+func NewRollbackCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "update service to use an earlier task revision",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			rollbackServices(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+
+	cobraCmd.Flags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
+	cobraCmd.Flags().StringSliceVarP(&cmd.SelectedServices, "service", "s", []string{}, "Service(s) to rollback. Separated by comma")
+	cobraCmd.Flags().Int64Var(&rollbackRevision, "to", 0, "Revision to rollback to (default: previous revision)")
+	cobraCmd.Flags().BoolVar(&rollbackListOnly, "list", false, "Only list the recent revisions")
+	cobraCmd.Flags().StringVar(&releaseReason, "reason", "", "Reason of the rollback, shown to whoever finds the environment locked")
+	cobraCmd.Flags().Int64Var(&rollbackHistory, "history", 10, "Number of revisions to list")
+	cobraCmd.Flags().BoolVar(&waitDeploy, "wait", false, "Wait for services to reach a steady state")
+	cobraCmd.Flags().DurationVar(&waitTimeout, "timeout", 15*time.Minute, "Maximum time to wait for services to stabilize [--timeout 15m]")
+	err := cobraCmd.MarkFlagRequired("service")
+	if err != nil {
+		fmt.Printf("rollback.missing.service err:%v\n", err)
+	}
+
+	cmd.AddCommand(cobraCmd)
+}
+
+func rollbackServices(cmd *Command) {
+	if rollbackRevision > 0 && len(cmd.SelectedServices) > 1 {
+		log.Fatal("--to can only be used with a single service")
+	}
+
+	cmd.AWSSession.GetServices(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, cmd.GTenv, false, cmd.SelectedServices...)
+
+	release := func() {}
+	if !rollbackListOnly {
+		release = cmd.AcquireLock("rollback")
+		defer release()
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Service", "Current Revision", "New Revision", "Current Image", "Desired Image", "status"})
+
+	waitTargets := make(map[string]string)
+	serviceRows := make(map[string]int)
+	rows := &deployRows{}
+	var rollbackFailed bool
+	// audit records and the row holding their final status
+	records := make([]gtdhistory.Record, 0)
+	recordRows := make([]int, 0)
+
+	for _, aService := range cmd.Services.Services {
+		if aService.TaskDefinition == nil {
+			continue
+		}
+
+		currentRevision := fmt.Sprintf("%s:%d", *aService.TaskDefinition.Family, *aService.TaskDefinition.Revision)
+		revisions, err := cmd.AWSSession.ListTaskDefinitionRevisions(cmd.AWSSession.Svc, *aService.TaskDefinition.Family, rollbackHistory)
+		if err != nil {
+			log.Fatal(fmt.Errorf("error while listing revisions of %s\n%s", *aService.TaskDefinition.Family, err.Error()))
+		}
+
+		showRevisions(cmd, aService.Name, *aService.TaskDefinition.Revision, revisions)
+		if rollbackListOnly {
+			continue
+		}
+
+		target, err := rollbackTarget(cmd, aService.TaskDefinition, revisions)
+		if err != nil {
+			rollbackFailed = true
+			rows.AppendRow([]interface{}{
+				aService.Name,
+				currentRevision,
+				"-",
+				containerImages(aService.TaskDefinition.ContainerDefinitions),
+				"-",
+				err.Error()})
+			continue
+		}
+
+		newRevision := fmt.Sprintf("%s:%d", *target.Family, *target.Revision)
+		status := rollbackService(cmd, aService.Name, newRevision)
+		if strings.HasPrefix(status, "ROLLED BACK") {
+			waitTargets[aService.Name] = newRevision
+		} else {
+			rollbackFailed = true
+		}
+
+		serviceRows[aService.Name] = rows.AppendRow([]interface{}{
+			aService.Name,
+			currentRevision,
+			newRevision,
+			containerImages(aService.TaskDefinition.ContainerDefinitions),
+			containerImages(target.ContainerDefinitions),
+			status})
+
+		records = append(records, gtdhistory.Record{
+			Action:            "rollback",
+			Service:           aService.Name,
+			OldTaskDefinition: currentRevision,
+			NewTaskDefinition: newRevision,
+			OldImage:          containerImages(aService.TaskDefinition.ContainerDefinitions),
+			NewImage:          containerImages(target.ContainerDefinitions),
+		})
+		recordRows = append(recordRows, serviceRows[aService.Name])
+	}
+
+	if rollbackListOnly {
+		return
+	}
+
+	failures := make(map[string]error)
+	if waitDeploy {
+		failures = waitForServices(cmd, waitTargets, waitTimeout)
+		for name := range waitTargets {
+			if _, failed := failures[name]; failed {
+				rows.rows[serviceRows[name]][5] = "FAILED"
+			} else {
+				rows.rows[serviceRows[name]][5] = "STABLE"
+			}
+		}
+	}
+
+	for i := range records {
+		records[i].Result = fmt.Sprint(rows.rows[recordRows[i]][5])
+	}
+	cmd.RecordHistory(records...)
+
+	for _, row := range rows.rows {
+		t.AppendRow(row)
+	}
+
+	switch cmd.TableStyle {
+	case "light":
+		t.SetStyle(table.StyleLight)
+	case "color":
+		t.SetStyle(table.StyleColoredDark)
+	}
+	if t.Length() > cmd.ShowTableIndexAbove {
+		t.SetAutoIndex(true)
+	}
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 4, WidthMax: 30},
+		{Number: 5, WidthMax: 30},
+	})
+	t.Render()
+
+	for name, err := range failures {
+		log.Printf("%s: %v", name, err)
+	}
+	if len(failures) > 0 || rollbackFailed {
+		release()
+		os.Exit(1)
+	}
+}
+
+//rollbackTarget return the revision requested with --to,
+//or the ACTIVE revision preceding the current one, listed or not.
+func rollbackTarget(cmd *Command, current *ecs.TaskDefinition, revisions []*ecs.TaskDefinition) (*ecs.TaskDefinition, error) {
+	if rollbackRevision > 0 {
+		if rollbackRevision == *current.Revision {
+			return nil, fmt.Errorf("already on revision %d", rollbackRevision)
+		}
+		for _, revision := range revisions {
+			if *revision.Revision == rollbackRevision {
+				return revision, nil
+			}
+		}
+		// older than the listed revisions
+		taskDefinition, err := cmd.AWSSession.GetCurrentTaskDefinition(cmd.AWSSession.Svc, fmt.Sprintf("%s:%d", *current.Family, rollbackRevision))
+		if err != nil {
+			return nil, fmt.Errorf("revision %d not found", rollbackRevision)
+		}
+		if !strings.EqualFold(ecs.TaskDefinitionStatusActive, aws.StringValue(taskDefinition.TaskDefinition.Status)) {
+			return nil, fmt.Errorf("revision %d is %s", rollbackRevision, aws.StringValue(taskDefinition.TaskDefinition.Status))
+		}
+		return taskDefinition.TaskDefinition, nil
+	}
+
+	// the current revision may be older than the listed ones
+	previous, err := cmd.AWSSession.PreviousTaskDefinitionArn(cmd.AWSSession.Svc, *current.Family, *current.Revision)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold("", previous) {
+		return nil, fmt.Errorf("no previous revision")
+	}
+	for _, revision := range revisions {
+		if aws.StringValue(revision.TaskDefinitionArn) == previous {
+			return revision, nil
+		}
+	}
+	taskDefinition, err := cmd.AWSSession.GetCurrentTaskDefinition(cmd.AWSSession.Svc, previous)
+	if err != nil {
+		return nil, err
+	}
+	return taskDefinition.TaskDefinition, nil
+}
+
+//showRevisions print the recent revisions of a service's family
+func showRevisions(cmd *Command, serviceName string, currentRevision int64, revisions []*ecs.TaskDefinition) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle(serviceName)
+	t.AppendHeader(table.Row{"Revision", "Image", "Registered At", ""})
+
+	for _, revision := range revisions {
+		var marker string
+		if *revision.Revision == currentRevision {
+			marker = "current"
+		}
+		var registeredAt string
+		if revision.RegisteredAt != nil {
+			registeredAt = revision.RegisteredAt.Local().Format("2006-01-02 15:04:05")
+		}
+		t.AppendRow([]interface{}{
+			fmt.Sprintf("%s:%d", *revision.Family, *revision.Revision),
+			containerImages(revision.ContainerDefinitions),
+			registeredAt,
+			marker})
+	}
+
+	switch cmd.TableStyle {
+	case "light":
+		t.SetStyle(table.StyleLight)
+	case "color":
+		t.SetStyle(table.StyleColoredDark)
+	}
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, Align: text.AlignLeft},
+	})
+	t.Render()
+}
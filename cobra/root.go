@@ -147,9 +147,20 @@ func NewCommand(version string) *Command {
 	}
 
 	NewDeployCommand(cmd)
+	NewRollbackCommand(cmd)
+	NewLockCommand(cmd)
 	NewStatusCommand(cmd)
 	NewInvalidateCommand(cmd)
 	NewListInvalidationCommand(cmd)
 	NewEncryptVarCommand(cmd)
+	NewHistoryCommand(cmd)
+	NewChangelogCommand(cmd)
+	NewPromoteCommand(cmd)
+	NewParamsCommand(cmd)
+	NewPruneRevisionsCommand(cmd)
+	NewScaleCommand(cmd)
+	NewEnvCommand(cmd)
+	NewRunTaskCommand(cmd)
+	NewLogsCommand(cmd)
 	return cmd
 }
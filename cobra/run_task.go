@@ -0,0 +1,224 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	gtdaws "github.com/gpkfr/goretdep/aws"
+	"github.com/gpkfr/goretdep/config"
+	"github.com/gpkfr/goretdep/gtdhistory"
+	"github.com/spf13/cobra"
+)
+
+const runTaskPollInterval = 3 * time.Second
+
+var (
+	runTaskService   string
+	runTaskContainer string
+	runTaskEnv       []string
+)
+
+//NewRunTaskCommand bind the run-task command
+func NewRunTaskCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "run-task -s service [-- command args...]",
+		Short: "Run a one-off task from the task definition of a service",
+		Args:  cobra.ArbitraryArgs,
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			runTask(cmd, args)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+
+	cobraCmd.Flags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
+	cobraCmd.Flags().StringVarP(&runTaskService, "service", "s", "", "Service whose task definition and network configuration are used")
+	cobraCmd.Flags().StringVar(&runTaskContainer, "container", "", "Container running the command (default: stack file 'container' or first container)")
+	cobraCmd.Flags().StringSliceVar(&runTaskEnv, "env-var", []string{}, "Environment variable(s) overridden [--env-var KEY=VALUE]")
+	cobraCmd.Flags().DurationVar(&waitTimeout, "timeout", time.Hour, "Maximum time to wait for the task to stop [--timeout 1h]")
+	if err := cobraCmd.MarkFlagRequired("service"); err != nil {
+		fmt.Printf("run-task.missing.service err:%v\n", err)
+	}
+
+	cmd.AddCommand(cobraCmd)
+}
+
+func runTask(cmd *Command, command []string) {
+	cmd.AWSSession.GetServices(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, cmd.GTenv, false, runTaskService)
+
+	var aService *config.Service
+	for i := range cmd.Services.Services {
+		if strings.EqualFold(runTaskService, cmd.Services.Services[i].Name) && cmd.Services.Services[i].TaskDefinition != nil {
+			aService = &cmd.Services.Services[i]
+		}
+	}
+	if aService == nil {
+		log.Fatalf("service %s not found in %s", runTaskService, cmd.GTenv)
+	}
+
+	container := runTaskContainer
+	if strings.EqualFold("", container) {
+		containers, err := gtdaws.SelectContainerDefinitions(aService.TaskDefinition.ContainerDefinitions, serviceContainers(aService)...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		container = aws.StringValue(containers[0].Name)
+	}
+
+	env := make([]*ecs.KeyValuePair, 0, len(runTaskEnv))
+	for _, value := range runTaskEnv {
+		kv := strings.SplitN(value, "=", 2)
+		if len(kv) != 2 {
+			log.Fatalf("invalid environment variable %s, expected KEY=VALUE", value)
+		}
+		env = append(env, &ecs.KeyValuePair{Name: aws.String(kv[0]), Value: aws.String(kv[1])})
+	}
+
+	taskDefinition := fmt.Sprintf("%s:%d", *aService.TaskDefinition.Family, *aService.TaskDefinition.Revision)
+	exitCode, err := runServiceTask(cmd, aService, taskDefinition, container, command, env, "", waitTimeout)
+
+	result := fmt.Sprintf("exit %d", exitCode)
+	if err != nil {
+		result = err.Error()
+	}
+	cmd.RecordHistory(gtdhistory.Record{
+		Action:            "run-task",
+		Service:           aService.Name,
+		OldTaskDefinition: taskDefinition,
+		Result:            fmt.Sprintf("%s: %s", strings.Join(command, " "), result),
+	})
+
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	os.Exit(int(exitCode))
+}
+
+//runServiceTask run a one-off task of taskDefinition with the network configuration of aService,
+//print the logs of container (prefixed) until the task stops and Return the container exit code.
+//command and env override the container's ones when given.
+//The task is stopped when timeout expires or gtd is interrupted.
+func runServiceTask(cmd *Command, aService *config.Service, taskDefinition, container string, command []string, env []*ecs.KeyValuePair, prefix string, timeout time.Duration) (int64, error) {
+	serviceName := aService.Name
+	service, err := cmd.AWSSession.DescribeAWSService(cmd.AWSSession.Svc, &serviceName, &cmd.Services.ECSCluster)
+	if err != nil {
+		return -1, err
+	}
+
+	described, err := cmd.AWSSession.GetCurrentTaskDefinition(cmd.AWSSession.Svc, taskDefinition)
+	if err != nil {
+		return -1, err
+	}
+	containers, err := gtdaws.SelectContainerDefinitions(described.TaskDefinition.ContainerDefinitions, container)
+	if err != nil {
+		return -1, err
+	}
+
+	override := &ecs.ContainerOverride{Name: aws.String(container)}
+	if len(command) > 0 {
+		override.Command = aws.StringSlice(command)
+	}
+	if len(env) > 0 {
+		override.Environment = env
+	}
+
+	task, err := cmd.AWSSession.RunServiceTask(cmd.AWSSession.Svc, service, cmd.Services.ECSCluster, taskDefinition, &ecs.TaskOverride{
+		ContainerOverrides: []*ecs.ContainerOverride{override},
+	})
+	if err != nil {
+		return -1, err
+	}
+	taskArn := aws.StringValue(task.TaskArn)
+	log.Printf("%sTask %s started (%s)", prefix, gtdaws.TaskID(taskArn), taskDefinition)
+
+	stream, err := gtdaws.ContainerLogStream(containers[0], gtdaws.TaskID(taskArn))
+	if err != nil {
+		log.Printf("%slogs unavailable: %v", prefix, err)
+	}
+
+	var token *string
+	printLogs := func() {
+		if stream == nil {
+			return
+		}
+		for {
+			events, next, err := cmd.AWSSession.GetLogEvents(stream, token)
+			if err != nil {
+				log.Printf("%slogs: %v", prefix, err)
+				return
+			}
+			for _, event := range events {
+				fmt.Printf("%s%s\n", prefix, strings.TrimRight(aws.StringValue(event.Message), "\n"))
+			}
+			// the same token is returned once every event is read
+			if len(events) == 0 || aws.StringValue(next) == aws.StringValue(token) {
+				token = next
+				return
+			}
+			token = next
+		}
+	}
+
+	// never leave the task running behind us
+	stopTask := func(reason string) {
+		log.Printf("%sStopping task %s: %s", prefix, gtdaws.TaskID(taskArn), reason)
+		if err := cmd.AWSSession.StopTask(cmd.AWSSession.Svc, cmd.Services.ECSCluster, taskArn, fmt.Sprintf("gtd: %s", reason)); err != nil {
+			log.Printf("%sunable to stop task %s: %v", prefix, gtdaws.TaskID(taskArn), err)
+		}
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		select {
+		case sig := <-interrupt:
+			stopTask(fmt.Sprintf("interrupted (%s)", sig))
+			printLogs()
+			return -1, fmt.Errorf("task %s stopped: interrupted", gtdaws.TaskID(taskArn))
+		case <-time.After(runTaskPollInterval):
+		}
+		printLogs()
+
+		task, err = cmd.AWSSession.DescribeTask(cmd.AWSSession.Svc, cmd.Services.ECSCluster, taskArn)
+		if err != nil {
+			// keep polling, the error may be transient
+			log.Printf("%s%v", prefix, err)
+		} else if aws.StringValue(task.LastStatus) == ecs.DesiredStatusStopped {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			stopTask(fmt.Sprintf("still running after %s", timeout))
+			return -1, fmt.Errorf("task %s stopped: still running after %s", gtdaws.TaskID(taskArn), timeout)
+		}
+	}
+
+	// the last events may reach CloudWatch after the task stopped
+	time.Sleep(runTaskPollInterval)
+	printLogs()
+
+	for _, c := range task.Containers {
+		if aws.StringValue(c.Name) != container {
+			continue
+		}
+		if c.ExitCode == nil {
+			return -1, fmt.Errorf("task %s stopped without exit code: %s %s", gtdaws.TaskID(taskArn), aws.StringValue(task.StoppedReason), aws.StringValue(c.Reason))
+		}
+		log.Printf("%sTask %s stopped, exit code %d", prefix, gtdaws.TaskID(taskArn), aws.Int64Value(c.ExitCode))
+		return aws.Int64Value(c.ExitCode), nil
+	}
+	return -1, fmt.Errorf("container %s not found in task %s", container, gtdaws.TaskID(taskArn))
+}
@@ -0,0 +1,175 @@
+package cobra
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/gpkfr/goretdep/gtdhistory"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+var scaleCount string
+
+//NewScaleCommand bind the scale command
+func NewScaleCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "scale",
+		Short: "Change the desired count of services",
+
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			scaleServices(cmd)
+		},
+		PreRun: func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckEnv()
+			cmd.GetAWSSession()
+		},
+	}
+
+	cobraCmd.Flags().StringVarP(&cmd.GTenv, "env", "e", "", "Environment to use")
+	cobraCmd.Flags().StringSliceVarP(&cmd.SelectedServices, "service", "s", []string{}, "Service(s) to scale. Separated by comma")
+	cobraCmd.Flags().StringVar(&scaleCount, "count", "", "Desired count [--count 3], or a change of the current one [--count +1, --count -1]")
+	cobraCmd.Flags().BoolVar(&waitDeploy, "wait", false, "Wait until the running count matches the desired count")
+	cobraCmd.Flags().DurationVar(&waitTimeout, "timeout", 15*time.Minute, "Maximum time to wait for services to stabilize [--timeout 15m]")
+	cobraCmd.Flags().StringVar(&releaseReason, "reason", "", "Reason of the scaling, shown to whoever finds the environment locked")
+	for _, name := range []string{"service", "count"} {
+		if err := cobraCmd.MarkFlagRequired(name); err != nil {
+			fmt.Printf("scale.missing.%s err:%v\n", name, err)
+		}
+	}
+
+	cmd.AddCommand(cobraCmd)
+}
+
+//desiredCount apply --count to the current desired count
+func desiredCount(count string, current int64) (int64, error) {
+	value, err := strconv.ParseInt(count, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid count %s", count)
+	}
+	if strings.HasPrefix(count, "+") || strings.HasPrefix(count, "-") {
+		value += current
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("desired count would be %d", value)
+	}
+	return value, nil
+}
+
+func scaleServices(cmd *Command) {
+	cmd.AWSSession.GetServices(&cmd.Services, &cmd.Repositories, &cmd.ChildTasks, cmd.GTenv, false, cmd.SelectedServices...)
+
+	release := cmd.AcquireLock("scale")
+	defer release()
+
+	rows := &deployRows{}
+	serviceRows := make(map[string]int)
+	waitTargets := make(map[string]string)
+	var failed bool
+
+	for _, aService := range cmd.Services.Services {
+		if aService.TaskDefinition == nil {
+			continue
+		}
+
+		serviceName := aService.Name
+		service, err := cmd.AWSSession.DescribeAWSService(cmd.AWSSession.Svc, &serviceName, &cmd.Services.ECSCluster)
+		if err != nil {
+			failed = true
+			rows.AppendRow([]interface{}{aService.Name, "-", "-", aService.RunningCount, "-", err.Error()})
+			continue
+		}
+		before := aws.Int64Value(service.DesiredCount)
+
+		after, err := desiredCount(scaleCount, before)
+		if err != nil {
+			failed = true
+			rows.AppendRow([]interface{}{aService.Name, before, "-", aws.Int64Value(service.RunningCount), "-", err.Error()})
+			continue
+		}
+
+		status := "UNCHANGED"
+		if after != before {
+			if _, err := cmd.AWSSession.SetDesiredCount(cmd.AWSSession.Svc, &serviceName, &cmd.Services.ECSCluster, after); err != nil {
+				failed = true
+				status = fmt.Sprintf("FAILED (%v)", err)
+			} else {
+				status = "SCALED"
+				waitTargets[aService.Name] = ""
+			}
+		}
+
+		serviceRows[aService.Name] = rows.AppendRow([]interface{}{
+			aService.Name,
+			before,
+			after,
+			aws.Int64Value(service.RunningCount),
+			"-",
+			status})
+	}
+
+	if waitDeploy {
+		failures := waitForServices(cmd, waitTargets, waitTimeout)
+		for name := range waitTargets {
+			if err, ko := failures[name]; ko {
+				failed = true
+				log.Printf("%s: %v", name, err)
+				rows.rows[serviceRows[name]][5] = "FAILED"
+			} else {
+				rows.rows[serviceRows[name]][5] = "STABLE"
+			}
+		}
+	}
+
+	records := make([]gtdhistory.Record, 0, len(waitTargets))
+	for name := range waitTargets {
+		row := rows.rows[serviceRows[name]]
+		records = append(records, gtdhistory.Record{
+			Action:  "scale",
+			Service: name,
+			Result:  fmt.Sprintf("%v -> %v %v", row[1], row[2], row[5]),
+		})
+	}
+	cmd.RecordHistory(records...)
+
+	renderScaleTable(cmd, rows, serviceRows)
+
+	if failed {
+		release()
+		os.Exit(1)
+	}
+}
+
+//renderScaleTable refresh the running counts (column 4) of the services
+//then print the rows of a scaling
+func renderScaleTable(cmd *Command, rows *deployRows, serviceRows map[string]int) {
+	for name, row := range serviceRows {
+		serviceName := name
+		if service, err := cmd.AWSSession.DescribeAWSService(cmd.AWSSession.Svc, &serviceName, &cmd.Services.ECSCluster); err == nil {
+			rows.rows[row][4] = aws.Int64Value(service.RunningCount)
+		}
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Service", "Desired count", "New desired count", "Running count", "Running count (after)", "status"})
+	for _, row := range rows.rows {
+		t.AppendRow(row)
+	}
+
+	switch cmd.TableStyle {
+	case "light":
+		t.SetStyle(table.StyleLight)
+	case "color":
+		t.SetStyle(table.StyleColoredDark)
+	}
+	if t.Length() > cmd.ShowTableIndexAbove {
+		t.SetAutoIndex(true)
+	}
+	t.Render()
+}
@@ -1,8 +1,14 @@
 package cobra
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	gtdaws "github.com/gpkfr/goretdep/aws"
+	"github.com/gpkfr/goretdep/gtddocker"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
 )
@@ -35,15 +41,18 @@ func status(cmd *Command) {
 
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Service", "Family", "Revision", "Current Image", "status", "Running count"})
+	t.AppendHeader(table.Row{"Service", "Family", "Revision", "Current Image", "Digest", "status", "Running count"})
+	registry := gtddocker.NewRegistryClient(cmd.DockerHubAuthConfig)
 	// loop under services
 	for _, aService := range cmd.Services.Services {
 		if aService.TaskDefinition != nil {
+			images, digests := pinnedImagesStatus(cmd, registry, aService.TaskDefinition.ContainerDefinitions)
 			t.AppendRow([]interface{}{
 				aService.Name,
 				*aService.TaskDefinition.Family,
 				*aService.TaskDefinition.Revision,
-				*aService.TaskDefinition.ContainerDefinitions[0].Image,
+				images,
+				digests,
 				aService.Status,
 				aService.RunningCount})
 		}
@@ -63,3 +72,42 @@ func status(cmd *Command) {
 	t.Render()
 
 }
+
+//pinnedImagesStatus Return the images of containers without their digest,
+//and their short digest. A digest is flagged when its tag now points to another one.
+func pinnedImagesStatus(cmd *Command, registry *gtddocker.RegistryClient, containers []*ecs.ContainerDefinition) (string, string) {
+	tagged := make([]*ecs.ContainerDefinition, 0, len(containers))
+	digests := make([]string, 0, len(containers))
+
+	for _, container := range containers {
+		image := aws.StringValue(container.Image)
+		tagged = append(tagged, &ecs.ContainerDefinition{Name: container.Name, Image: aws.String(gtddocker.Tagged(image))})
+
+		digest := gtddocker.Digest(image)
+		if strings.EqualFold("", digest) {
+			digests = append(digests, "-")
+			continue
+		}
+
+		var current string
+		var err error
+		if gtdaws.IsECRImage(image) {
+			current, err = cmd.AWSSession.ECRImageDigest(gtddocker.Tagged(image))
+		} else {
+			current, err = registry.ImageDigest(gtddocker.Tagged(image))
+		}
+
+		switch {
+		case err != nil:
+			digests = append(digests, fmt.Sprintf("%s (tag unknown: %v)", gtddocker.ShortDigest(digest), err))
+		case strings.EqualFold("", current):
+			digests = append(digests, fmt.Sprintf("%s (tag not found)", gtddocker.ShortDigest(digest)))
+		case !strings.EqualFold(current, digest):
+			digests = append(digests, fmt.Sprintf("%s DRIFT: tag now %s", gtddocker.ShortDigest(digest), gtddocker.ShortDigest(current)))
+		default:
+			digests = append(digests, gtddocker.ShortDigest(digest))
+		}
+	}
+
+	return containerImages(tagged), strings.Join(digests, "\n")
+}
@@ -0,0 +1,113 @@
+package cobra
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/gpkfr/goretdep/aws"
+	"github.com/jedib0t/go-pretty/v6/progress"
+)
+
+const waitPollInterval = 10 * time.Second
+
+//waitForServices poll the deployments of each service until the PRIMARY one
+//is the only deployment left and runs the desired count of tasks.
+//targets map a service name to the task definition (family:revision) expected.
+//It returns the error of every service that did not stabilize before timeout.
+func waitForServices(cmd *Command, targets map[string]string, timeout time.Duration) map[string]error {
+	failures := make(map[string]error)
+	if len(targets) == 0 {
+		return failures
+	}
+
+	pw := progress.NewWriter()
+	pw.SetAutoStop(false)
+	pw.SetTrackerLength(25)
+	pw.SetUpdateFrequency(500 * time.Millisecond)
+	pw.SetOutputWriter(os.Stdout)
+	pw.SetStyle(progress.StyleDefault)
+
+	trackers := make(map[string]*progress.Tracker)
+	for name := range targets {
+		trackers[name] = &progress.Tracker{Message: name, Total: 1, Units: progress.UnitsDefault}
+		pw.AppendTracker(trackers[name])
+	}
+
+	go pw.Render()
+
+	deadline := time.Now().Add(timeout)
+	pending := make(map[string]string)
+	for name, taskDefinition := range targets {
+		pending[name] = taskDefinition
+	}
+
+	for {
+		for name, taskDefinition := range pending {
+			serviceName := name
+			service, err := cmd.AWSSession.DescribeAWSService(cmd.AWSSession.Svc, &serviceName, &cmd.Services.ECSCluster)
+			if err != nil {
+				// keep polling, the error may be transient
+				trackers[name].UpdateMessage(fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+
+			state := serviceDeploymentState(cmd, service, taskDefinition)
+			trackers[name].UpdateTotal(state.DesiredCount)
+			trackers[name].SetValue(state.RunningCount)
+			trackers[name].UpdateMessage(fmt.Sprintf("%s [%s] deployments: %d running: %d/%d pending: %d",
+				name, state.RolloutState, state.Deployments, state.RunningCount, state.DesiredCount, state.PendingCount))
+
+			switch {
+			case state.Stable:
+				trackers[name].UpdateMessage(fmt.Sprintf("%s stable on %s", name, taskDefinition))
+				trackers[name].MarkAsDone()
+				delete(pending, name)
+			case state.Failed:
+				failures[name] = fmt.Errorf("deployment failed: %s", state.Reason)
+				trackers[name].UpdateMessage(fmt.Sprintf("%s FAILED: %s", name, state.Reason))
+				trackers[name].MarkAsDone()
+				delete(pending, name)
+			}
+		}
+
+		if len(pending) == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			for name := range pending {
+				failures[name] = fmt.Errorf("not stable after %s", timeout)
+				trackers[name].UpdateMessage(fmt.Sprintf("%s TIMEOUT after %s", name, timeout))
+				trackers[name].MarkAsDone()
+			}
+			break
+		}
+		time.Sleep(waitPollInterval)
+	}
+
+	// let the progress writer render the final state
+	time.Sleep(time.Second)
+	pw.Stop()
+	for pw.IsRenderInProgress() {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return failures
+}
+
+//serviceDeploymentState Return the rollout state of service,
+//failed when the tasks of its PRIMARY deployment keep stopping
+func serviceDeploymentState(cmd *Command, service *ecs.Service, taskDefinition string) *aws.ServiceDeploymentState {
+	state := aws.DeploymentState(service, taskDefinition)
+	if state.Stable || state.Failed || state.DeploymentID == "" {
+		return state
+	}
+
+	// the error may be transient, the next poll counts again
+	if failed, err := cmd.AWSSession.CountFailedTasks(cmd.AWSSession.Svc, cmd.Services.ECSCluster, state.DeploymentID); err == nil {
+		state.StoppedTasks(failed)
+	}
+	return state
+}
@@ -5,6 +5,8 @@ import (
 	"io"
 	"log"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"gopkg.in/yaml.v3"
@@ -17,26 +19,64 @@ type (
 	}
 
 	Service struct {
-		Name                 string  `yaml:"name"`
-		Registry             string  `yaml:"registry"`
-		Provider             string  `yaml:"provider,omitempty"`
-		IgnoreDeploy         bool    `yaml:"ignore,omitempty"`
-		UpdateECR            string  `yaml:"update_ecr,omitempty"`
-		UpdateChildTask      bool    `yaml:"update_child_task,omitempty"`
-		Labels               []Label `yaml:"labels,omitempty"`
-		TaskExecutionRoleArn string  `yaml:"task_execution_role_arn,omitempty"`
-		TaskRoleArn          string  `yaml:"task_role_arn,omitempty"`
+		Name                 string   `yaml:"name"`
+		Registry             string   `yaml:"registry"`
+		Provider             string   `yaml:"provider,omitempty"`
+		IgnoreDeploy         bool     `yaml:"ignore,omitempty"`
+		AlwaysOn             bool     `yaml:"always_on,omitempty"`
+		UpdateECR            string   `yaml:"update_ecr,omitempty"`
+		UpdateChildTask      bool     `yaml:"update_child_task,omitempty"`
+		Labels               []Label  `yaml:"labels,omitempty"`
+		TaskExecutionRoleArn string   `yaml:"task_execution_role_arn,omitempty"`
+		TaskRoleArn          string   `yaml:"task_role_arn,omitempty"`
+		Container            string   `yaml:"container,omitempty"`
+		DependsOn            []string `yaml:"depends_on,omitempty"`
+		Wave                 int      `yaml:"wave,omitempty"`
+		Github               string   `yaml:"github,omitempty"`
+		SourcePath           string   `yaml:"source_path,omitempty"`
+		EnvFiles             []string `yaml:"env_files,omitempty"`
+		EnvMode              string   `yaml:"env_mode,omitempty"`
+		EnvUnset             []string `yaml:"env_unset,omitempty"`
+		Hooks                Hooks    `yaml:"hooks,omitempty"`
 		TaskARN              string
 		Status               string
 		RunningCount         int64
 		TaskDefinition       *ecs.TaskDefinition
+		TaskDefinitionTags   []*ecs.Tag
+		DesiredImage         string
+		ParamARNs            map[string]string
+		TasksEnv             []*ecs.KeyValuePair
+		SecretsEnv           []*ecs.Secret
+	}
+
+	//Notification is a webhook notified of deploys (type: webhook, slack or teams)
+	Notification struct {
+		Type   string   `yaml:"type" mapstructure:"type"`
+		URL    string   `yaml:"url" mapstructure:"url"`
+		Events []string `yaml:"events,omitempty" mapstructure:"events"`
+	}
+
+	//Hook is a one-off task run with the new revision of a service
+	Hook struct {
+		Name      string   `yaml:"name,omitempty"`
+		Command   []string `yaml:"command"`
+		Container string   `yaml:"container,omitempty"`
+		Timeout   string   `yaml:"timeout,omitempty"`
+	}
+
+	Hooks struct {
+		PreDeploy  []Hook `yaml:"pre_deploy,omitempty"`
+		PostDeploy []Hook `yaml:"post_deploy,omitempty"`
 	}
 
 	Services struct {
-		Github     string `yaml:"github,omitempty"`
-		ECSCluster string `yaml:"ecs_cluster"`
-		ECSRegion  string `yaml:"ecs_region"`
-		Services   []Service
+		Github        string         `yaml:"github,omitempty"`
+		ECSCluster    string         `yaml:"ecs_cluster"`
+		ECSRegion     string         `yaml:"ecs_region"`
+		AutoRollback  bool           `yaml:"auto_rollback,omitempty"`
+		PinDigest     bool           `yaml:"pin_digest,omitempty"`
+		Notifications []Notification `yaml:"notifications,omitempty"`
+		Services      []Service
 	}
 
 	Repository struct {
@@ -56,6 +96,7 @@ type (
 		Name          string `yaml:"name"`
 		ParentService string `yaml:"parent"`
 		IgnoreDeploy  bool   `yaml:"ignore,omitempty"`
+		Container     string `yaml:"container,omitempty"`
 	}
 
 	ChildTasks struct {
@@ -87,6 +128,10 @@ func LoadService(services *Services, repositories *Repositories, childtasks *Chi
 		log.Fatal(fmt.Errorf("service:  could not decode config file %s: %v", configFilePath, err))
 	}
 
+	if _, err := services.DeployWaves(); err != nil {
+		return fmt.Errorf("service: invalid deploy order in %s: %v", configFilePath, err)
+	}
+
 	_, _ = f.Seek(0, io.SeekStart)
 	decoder = yaml.NewDecoder(f)
 	err = decoder.Decode(repositories)
@@ -104,6 +149,77 @@ func LoadService(services *Services, repositories *Repositories, childtasks *Chi
 	return nil
 }
 
+//DeployWaves group the services by deployment wave.
+//A service is deployed in its own 'wave' and after every service it 'depends_on'.
+//It returns the indexes of services, wave by wave, or an error on a dependency cycle.
+func (services *Services) DeployWaves() ([][]int, error) {
+	index := make(map[string]int, len(services.Services))
+	for i, s := range services.Services {
+		index[strings.ToLower(s.Name)] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	waves := make([]int, len(services.Services))
+	state := make([]int, len(services.Services))
+
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		name := services.Services[i].Name
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle %s", strings.Join(append(path, name), " -> "))
+		}
+
+		state[i] = visiting
+		// a copy, sibling dependencies must not share the backing array of path
+		next := append(append(make([]string, 0, len(path)+1), path...), name)
+		wave := services.Services[i].Wave
+		for _, dependency := range services.Services[i].DependsOn {
+			j, ok := index[strings.ToLower(dependency)]
+			if !ok {
+				return fmt.Errorf("%s depends on unknown service %s", name, dependency)
+			}
+			if err := visit(j, next); err != nil {
+				return err
+			}
+			if waves[j]+1 > wave {
+				wave = waves[j] + 1
+			}
+		}
+		waves[i] = wave
+		state[i] = visited
+		return nil
+	}
+
+	for i := range services.Services {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	numbers := make([]int, 0)
+	grouped := make(map[int][]int)
+	for i, wave := range waves {
+		if _, ok := grouped[wave]; !ok {
+			numbers = append(numbers, wave)
+		}
+		grouped[wave] = append(grouped[wave], i)
+	}
+	sort.Ints(numbers)
+
+	ordered := make([][]int, 0, len(numbers))
+	for _, wave := range numbers {
+		ordered = append(ordered, grouped[wave])
+	}
+	return ordered, nil
+}
+
 func exists(path string) (bool, error) {
 	_, err := os.Stat(path)
 	if err == nil {
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeployWaves(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []Service
+		waves    [][]int
+		err      string
+	}{
+		{
+			name:     "no order",
+			services: []Service{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			waves:    [][]int{{0, 1, 2}},
+		},
+		{
+			name:     "wave field",
+			services: []Service{{Name: "a", Wave: 2}, {Name: "b"}, {Name: "c", Wave: 2}},
+			waves:    [][]int{{1}, {0, 2}},
+		},
+		{
+			name:     "depends_on chain",
+			services: []Service{{Name: "c", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}, {Name: "a"}},
+			waves:    [][]int{{2}, {1}, {0}},
+		},
+		{
+			name:     "depends_on after a wave",
+			services: []Service{{Name: "a", Wave: 1}, {Name: "b", DependsOn: []string{"a"}}, {Name: "c"}},
+			waves:    [][]int{{2}, {0}, {1}},
+		},
+		{
+			name:     "wave later than dependencies",
+			services: []Service{{Name: "a"}, {Name: "b", DependsOn: []string{"a"}, Wave: 3}},
+			waves:    [][]int{{0}, {1}},
+		},
+		{
+			name:     "dependency names ignore case",
+			services: []Service{{Name: "API"}, {Name: "worker", DependsOn: []string{"api"}}},
+			waves:    [][]int{{0}, {1}},
+		},
+		{
+			name:     "unknown dependency",
+			services: []Service{{Name: "a", DependsOn: []string{"b"}}},
+			err:      "a depends on unknown service b",
+		},
+		{
+			name:     "self dependency",
+			services: []Service{{Name: "a", DependsOn: []string{"a"}}},
+			err:      "dependency cycle a -> a",
+		},
+		{
+			name: "cycle",
+			services: []Service{
+				{Name: "a", DependsOn: []string{"b", "c"}},
+				{Name: "b", DependsOn: []string{"d"}},
+				{Name: "c", DependsOn: []string{"e"}},
+				{Name: "d"},
+				{Name: "e", DependsOn: []string{"a"}},
+			},
+			err: "dependency cycle a -> c -> e -> a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			services := &Services{Services: test.services}
+			waves, err := services.DeployWaves()
+
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(waves, test.waves) {
+				t.Errorf("expected waves %v, got %v", test.waves, waves)
+			}
+		})
+	}
+}
@@ -13,23 +13,22 @@ import (
 	"github.com/docker/docker/pkg/term"
 )
 
-func TagLocalDockerImageFrom(dockerImageSource, dockerNewImageTag string) {
+func TagLocalDockerImageFrom(dockerImageSource, dockerNewImageTag string) error {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	ctx := context.Background()
-	if err := cli.ImageTag(ctx, dockerImageSource, dockerNewImageTag); err != nil {
-		log.Fatal(err)
-	}
+	return cli.ImageTag(ctx, dockerImageSource, dockerNewImageTag)
 }
 
 func PullFromPrivateRegistry(authConfig *types.AuthConfig, dockerImageName string) bool {
 
 	encodedJSON, err := json.Marshal(authConfig)
 	if err != nil {
-		panic(err)
+		log.Println(err)
+		return false
 	}
 
 	authStr := base64.URLEncoding.EncodeToString(encodedJSON)
@@ -37,12 +36,14 @@ func PullFromPrivateRegistry(authConfig *types.AuthConfig, dockerImageName strin
 	ctx := context.Background()
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
-		panic(err)
+		log.Println(err)
+		return false
 	}
 
 	out, err := cli.ImagePull(ctx, dockerImageName, types.ImagePullOptions{RegistryAuth: authStr})
 	if err != nil {
-		log.Fatal(err)
+		log.Println(err)
+		return false
 	}
 	defer out.Close()
 
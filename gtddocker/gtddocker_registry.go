@@ -0,0 +1,257 @@
+package gtddocker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+const dockerHubRegistry = "registry-1.docker.io"
+
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+//ImageReference is an image split in registry host, repository and tag or digest
+type ImageReference struct {
+	Registry   string
+	Repository string
+	Reference  string
+}
+
+//ParseImageReference split image [registry/]repository[:tag][@digest].
+//Docker Hub images get their registry and library/ prefix, tag default to latest.
+func ParseImageReference(image string) ImageReference {
+	ref := ImageReference{Registry: dockerHubRegistry, Reference: "latest"}
+
+	name := Tagged(image)
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		ref.Reference = name[i+1:]
+		name = name[:i]
+	}
+	// the digest wins over the tag
+	if digest := Digest(image); digest != "" {
+		ref.Reference = digest
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		name = parts[1]
+	}
+	if ref.Registry == "docker.io" || ref.Registry == "index.docker.io" {
+		ref.Registry = dockerHubRegistry
+	}
+	if ref.Registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = fmt.Sprintf("library/%s", name)
+	}
+	ref.Repository = name
+
+	return ref
+}
+
+//Tagged Return image without its digest: repository[:tag]
+func Tagged(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i]
+	}
+	return image
+}
+
+//Untagged Return image without its tag nor digest: [registry/]repository
+func Untagged(image string) string {
+	name := Tagged(image)
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		return name[:i]
+	}
+	return name
+}
+
+//Digest Return the digest of image, empty when it is not pinned
+func Digest(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[i+1:]
+	}
+	return ""
+}
+
+//ShortDigest Return the first 12 characters of a digest (sha256:0123456789ab)
+func ShortDigest(digest string) string {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) == 2 && len(parts[1]) > 12 {
+		return fmt.Sprintf("%s:%s", parts[0], parts[1][:12])
+	}
+	return digest
+}
+
+//IsDockerHub tell if the image is hosted on Docker Hub
+func (ref ImageReference) IsDockerHub() bool {
+	return ref.Registry == dockerHubRegistry
+}
+
+//RegistryClient query a registry v2 API
+type RegistryClient struct {
+	AuthConfig *types.AuthConfig
+	Client     *http.Client
+	// Scheme of the registry, https unless testing against a local registry
+	Scheme string
+}
+
+//NewRegistryClient Return a registry client using authConfig on Docker Hub
+func NewRegistryClient(authConfig *types.AuthConfig) *RegistryClient {
+	return &RegistryClient{
+		AuthConfig: authConfig,
+		Client:     &http.Client{Timeout: 30 * time.Second},
+		Scheme:     "https",
+	}
+}
+
+//ImageDigest Return the digest of the manifest image points to,
+//empty when the image does not exist
+func (registry *RegistryClient) ImageDigest(image string) (string, error) {
+	ref := ParseImageReference(image)
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", registry.Scheme, ref.Registry, ref.Repository, ref.Reference)
+
+	resp, err := registry.headManifest(manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := registry.token(ref, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", image, err)
+		}
+		resp, err = registry.headManifest(manifestURL, token)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		digest := resp.Header.Get("Docker-Content-Digest")
+		if digest == "" && strings.HasPrefix(ref.Reference, "sha256:") {
+			digest = ref.Reference
+		}
+		if digest == "" {
+			return "", fmt.Errorf("%s: registry did not return the manifest digest", image)
+		}
+		return digest, nil
+	case http.StatusNotFound:
+		return "", nil
+	default:
+		return "", fmt.Errorf("%s: registry answered %s", image, resp.Status)
+	}
+}
+
+func (registry *RegistryClient) headManifest(manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	resp, err := registry.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+//token answer a Bearer challenge, using the credentials on Docker Hub
+func (registry *RegistryClient) token(ref ImageReference, challenge string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	if !strings.EqualFold("bearer", scheme) || params["realm"] == "" {
+		return "", fmt.Errorf("unsupported registry authentication %q", challenge)
+	}
+
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", ref.Repository)
+	}
+	query.Set("scope", scope)
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s?%s", params["realm"], query.Encode()), nil)
+	if err != nil {
+		return "", err
+	}
+	if ref.IsDockerHub() && registry.AuthConfig != nil {
+		req.SetBasicAuth(registry.AuthConfig.Username, registry.AuthConfig.Password)
+	}
+
+	resp, err := registry.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request answered %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+//parseChallenge split a WWW-Authenticate header: Bearer realm="...",service="..."
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+
+	for _, param := range splitParams(parts[1]) {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+	}
+	return parts[0], params
+}
+
+//splitParams split on commas outside quotes (scopes may contain commas)
+func splitParams(value string) []string {
+	params := make([]string, 0)
+	var quoted bool
+	start := 0
+	for i, c := range value {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				params = append(params, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(params, value[start:])
+}
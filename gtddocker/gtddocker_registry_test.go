@@ -0,0 +1,169 @@
+package gtddocker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+//newTestRegistry start a local registry answering manifest HEAD requests with handler
+func newTestRegistry(t *testing.T, handler http.HandlerFunc) (*RegistryClient, string) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	registry := NewRegistryClient(nil)
+	registry.Scheme = "http"
+	return registry, strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestImageDigestFound(t *testing.T) {
+	registry, host := newTestRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/v2/team/app/manifests/1.0" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if !strings.Contains(r.Header.Get("Accept"), "application/vnd.docker.distribution.manifest.v2+json") {
+			t.Errorf("manifest media types not accepted: %s", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Docker-Content-Digest", testDigest)
+	})
+
+	digest, err := registry.ImageDigest(fmt.Sprintf("%s/team/app:1.0", host))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if digest != testDigest {
+		t.Errorf("expected digest %s, got %s", testDigest, digest)
+	}
+}
+
+func TestImageDigestNotFound(t *testing.T) {
+	registry, host := newTestRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	digest, err := registry.ImageDigest(fmt.Sprintf("%s/team/app:missing", host))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if digest != "" {
+		t.Errorf("expected no digest for a missing tag, got %s", digest)
+	}
+}
+
+func TestImageDigestBearerChallenge(t *testing.T) {
+	var tokenRequests int
+	var host string
+	registry, host := newTestRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			if r.URL.Query().Get("service") != "registry.test" || r.URL.Query().Get("scope") != "repository:team/app:pull" {
+				t.Errorf("unexpected token request %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"token": "secret-token"}`))
+		case "/v2/team/app/manifests/1.0":
+			if r.Header.Get("Authorization") != "Bearer secret-token" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="registry.test",scope="repository:team/app:pull"`, host))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", testDigest)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	digest, err := registry.ImageDigest(fmt.Sprintf("%s/team/app:1.0", host))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if digest != testDigest {
+		t.Errorf("expected digest %s, got %s", testDigest, digest)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("expected 1 token request, got %d", tokenRequests)
+	}
+}
+
+func TestImageDigestUnsupportedChallenge(t *testing.T) {
+	registry, host := newTestRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	if _, err := registry.ImageDigest(fmt.Sprintf("%s/team/app:1.0", host)); err == nil || !strings.Contains(err.Error(), "unsupported registry authentication") {
+		t.Errorf("expected an unsupported authentication error, got %v", err)
+	}
+}
+
+func TestImageDigestHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		image  string
+		header string
+		digest string
+		err    bool
+	}{
+		{name: "tag with header", image: "team/app:1.0", header: testDigest, digest: testDigest},
+		{name: "digest without header", image: "team/app@" + testDigest, digest: testDigest},
+		{name: "tag without header", image: "team/app:1.0", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry, host := newTestRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+				if test.header != "" {
+					w.Header().Set("Docker-Content-Digest", test.header)
+				}
+			})
+
+			digest, err := registry.ImageDigest(fmt.Sprintf("%s/%s", host, test.image))
+			if test.err {
+				if err == nil {
+					t.Errorf("expected an error, got digest %q", digest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if digest != test.digest {
+				t.Errorf("expected digest %s, got %s", test.digest, digest)
+			}
+		})
+	}
+}
+
+func TestImageDigestServerError(t *testing.T) {
+	registry, host := newTestRegistry(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	if _, err := registry.ImageDigest(fmt.Sprintf("%s/team/app:1.0", host)); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected a 500 error, got %v", err)
+	}
+}
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected ImageReference
+	}{
+		{"nginx", ImageReference{Registry: dockerHubRegistry, Repository: "library/nginx", Reference: "latest"}},
+		{"gutenbergtech/hapi:1.2", ImageReference{Registry: dockerHubRegistry, Repository: "gutenbergtech/hapi", Reference: "1.2"}},
+		{"docker.io/gutenbergtech/hapi:1.2", ImageReference{Registry: dockerHubRegistry, Repository: "gutenbergtech/hapi", Reference: "1.2"}},
+		{"localhost:5000/hapi:1.2", ImageReference{Registry: "localhost:5000", Repository: "hapi", Reference: "1.2"}},
+		{"1234.dkr.ecr.eu-west-1.amazonaws.com/hapi:1.2@" + testDigest, ImageReference{Registry: "1234.dkr.ecr.eu-west-1.amazonaws.com", Repository: "hapi", Reference: testDigest}},
+	}
+
+	for _, test := range tests {
+		if ref := ParseImageReference(test.image); ref != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.image, test.expected, ref)
+		}
+	}
+}
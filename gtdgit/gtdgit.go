@@ -0,0 +1,90 @@
+package gtdgit
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+//Commit is one line of the changelog
+type Commit struct {
+	SHA     string
+	Author  string
+	Date    string
+	Subject string
+}
+
+// tags embed the commit: develop-cbe267d-rct
+// all-digit parts are dates or build numbers (release-20240115), not commits
+var commitInTag = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+var hexLetter = regexp.MustCompile(`[a-f]`)
+
+//CommitFromImage Return the commit SHA embedded in the tag of image, empty when there is none
+func CommitFromImage(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	return CommitFromTag(image[i+1:])
+}
+
+//CommitFromTag Return the commit SHA embedded in tag, empty when there is none
+func CommitFromTag(tag string) string {
+	parts := strings.FieldsFunc(tag, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	})
+	for _, part := range parts {
+		if commitInTag.MatchString(part) && hexLetter.MatchString(part) {
+			return part
+		}
+	}
+	return ""
+}
+
+//CompareURL Return the GitHub compare page between two commits.
+//github is owner/repository or the repository URL.
+func CompareURL(github, from, to string) string {
+	if strings.EqualFold("", github) {
+		return ""
+	}
+	github = strings.TrimSuffix(strings.TrimSuffix(github, "/"), ".git")
+	if !strings.HasPrefix(github, "http://") && !strings.HasPrefix(github, "https://") {
+		github = fmt.Sprintf("https://github.com/%s", strings.TrimPrefix(github, "github.com/"))
+	}
+	return fmt.Sprintf("%s/compare/%s...%s", github, from, to)
+}
+
+//Log Return the commits reachable from to and not from from,
+//newest first, read from the git checkout at path.
+func Log(path, from, to string) ([]Commit, error) {
+	out, err := git(path, "log", "--no-merges", "--pretty=format:%h%x09%an%x09%ad%x09%s", "--date=short", fmt.Sprintf("%s..%s", from, to))
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]Commit, 0)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		commits = append(commits, Commit{SHA: fields[0], Author: fields[1], Date: fields[2], Subject: fields[3]})
+	}
+	return commits, nil
+}
+
+func git(path string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", path}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v %s (is %s up to date? try git fetch)", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()), path)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,42 @@
+package gtdgit
+
+import "testing"
+
+func TestCommitFromTag(t *testing.T) {
+	tests := []struct {
+		tag    string
+		commit string
+	}{
+		{"develop-cbe267d-rct", "cbe267d"},
+		{"cbe267d", "cbe267d"},
+		{"1.4.2_cbe267d4f1", "cbe267d4f1"},
+		{"release-20240115", ""},
+		{"release-20240115-cbe267d", "cbe267d"},
+		{"1234567", ""},
+		{"latest", ""},
+		{"develop-CBE267D", ""},
+	}
+
+	for _, test := range tests {
+		if commit := CommitFromTag(test.tag); commit != test.commit {
+			t.Errorf("%s: expected %q, got %q", test.tag, test.commit, commit)
+		}
+	}
+}
+
+func TestCommitFromImage(t *testing.T) {
+	tests := []struct {
+		image  string
+		commit string
+	}{
+		{"1234.dkr.ecr.eu-west-1.amazonaws.com/hapi:develop-cbe267d", "cbe267d"},
+		{"localhost:5000/hapi", ""},
+		{"hapi:release-20240115@sha256:0123456789abcdef", ""},
+	}
+
+	for _, test := range tests {
+		if commit := CommitFromImage(test.image); commit != test.commit {
+			t.Errorf("%s: expected %q, got %q", test.image, test.commit, commit)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package gtdhistory
+
+import (
+	"strings"
+	"time"
+)
+
+type (
+	//Record is one entry of the deployment history
+	Record struct {
+		Time              time.Time `json:"time"`
+		Action            string    `json:"action"`
+		User              string    `json:"user"`
+		Env               string    `json:"env"`
+		Cluster           string    `json:"cluster"`
+		Service           string    `json:"service"`
+		OldTaskDefinition string    `json:"old_task_definition,omitempty"`
+		NewTaskDefinition string    `json:"new_task_definition,omitempty"`
+		OldImage          string    `json:"old_image,omitempty"`
+		NewImage          string    `json:"new_image,omitempty"`
+		EnvFileHash       string    `json:"env_file_hash,omitempty"`
+		Result            string    `json:"result"`
+		Reason            string    `json:"reason,omitempty"`
+	}
+
+	//Filter select records, empty fields match everything
+	Filter struct {
+		Env     string
+		Service string
+		Since   time.Time
+	}
+
+	//Store keep the history
+	Store interface {
+		Append(records ...Record) error
+		Query(filter Filter) ([]Record, error)
+	}
+)
+
+//Match tell if record is selected by filter
+func (filter Filter) Match(record Record) bool {
+	if filter.Env != "" && !strings.EqualFold(filter.Env, record.Env) {
+		return false
+	}
+	if filter.Service != "" && !strings.EqualFold(filter.Service, record.Service) {
+		return false
+	}
+	if !filter.Since.IsZero() && record.Time.Before(filter.Since) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package gtdhistory
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterMatch(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	record := Record{Time: now, Env: "rct", Service: "svc-recette-hapi"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		match  bool
+	}{
+		{name: "empty filter", filter: Filter{}, match: true},
+		{name: "same env", filter: Filter{Env: "rct"}, match: true},
+		{name: "env case", filter: Filter{Env: "RCT"}, match: true},
+		{name: "other env", filter: Filter{Env: "prd"}},
+		{name: "same service", filter: Filter{Service: "svc-recette-hapi"}, match: true},
+		{name: "service case", filter: Filter{Service: "SVC-Recette-Hapi"}, match: true},
+		{name: "other service", filter: Filter{Service: "svc-recette-lms"}},
+		{name: "since before", filter: Filter{Since: now.Add(-time.Hour)}, match: true},
+		{name: "since at the record time", filter: Filter{Since: now}, match: true},
+		{name: "since after", filter: Filter{Since: now.Add(time.Second)}},
+		{name: "every field", filter: Filter{Env: "rct", Service: "svc-recette-hapi", Since: now.Add(-time.Hour)}, match: true},
+		{name: "one field differs", filter: Filter{Env: "rct", Service: "svc-recette-lms", Since: now.Add(-time.Hour)}},
+	}
+
+	for _, test := range tests {
+		if match := test.filter.Match(record); match != test.match {
+			t.Errorf("%s: expected %t, got %t", test.name, test.match, match)
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store := &FileStore{Path: filepath.Join(t.TempDir(), "history", "history.jsonl")}
+
+	// no file yet
+	records, err := store.Query(Filter{})
+	if err != nil || len(records) != 0 {
+		t.Fatalf("expected no record, got %v (%v)", records, err)
+	}
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	first := Record{
+		Time:              now,
+		Action:            "deploy",
+		User:              "alice@ci",
+		Env:               "rct",
+		Cluster:           "cluster",
+		Service:           "svc-recette-hapi",
+		OldTaskDefinition: "hapi:11",
+		NewTaskDefinition: "hapi:12",
+		NewImage:          "gutenbergtech/hapi:1.2",
+		Result:            "STABLE",
+	}
+	second := Record{Time: now.Add(time.Hour), Action: "rollback", Env: "rct", Service: "svc-recette-lms", Result: "ROLLED BACK"}
+	older := Record{Time: now.Add(-time.Hour), Action: "deploy", Env: "prd", Service: "svc-prod-hapi", Result: "STABLE"}
+
+	if err := store.Append(first, second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// appended later, older: Query sorts by time
+	if err := store.Append(older); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err = store.Query(Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []Record{older, first, second}; !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected %+v, got %+v", expected, records)
+	}
+
+	records, err = store.Query(Filter{Env: "rct", Since: now})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []Record{first, second}; !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected %+v, got %+v", expected, records)
+	}
+}
+
+func TestFileStoreInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := os.WriteFile(path, []byte("{\"action\":\"deploy\"}\n\nnot json\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &FileStore{Path: path}
+	if _, err := store.Query(Filter{}); err == nil {
+		t.Error("expected an error for the invalid line")
+	}
+}
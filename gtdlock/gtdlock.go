@@ -0,0 +1,97 @@
+package gtdlock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+)
+
+type (
+	//Lock describe who is releasing on an environment.
+	//Token identify the acquisition, only its holder can release the lock.
+	Lock struct {
+		Key       string    `json:"key"`
+		Token     string    `json:"token"`
+		Owner     string    `json:"owner"`
+		Reason    string    `json:"reason,omitempty"`
+		Command   string    `json:"command,omitempty"`
+		CreatedAt time.Time `json:"created_at"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+
+	//Backend store the locks
+	Backend interface {
+		//Acquire take the lock, unless it is held and not expired
+		Acquire(lock *Lock) error
+		//Release remove the lock acquired with token, whoever holds it when force is set
+		Release(key, token string, force bool) error
+		//Refresh store the new ExpiresAt of lock, as long as it still holds the lock
+		Refresh(lock *Lock) error
+		//Get return the current lock of key, nil when there is none
+		Get(key string) (*Lock, error)
+	}
+
+	//LockedError is returned when the lock is held by someone else
+	LockedError struct {
+		Lock *Lock
+	}
+)
+
+func (e *LockedError) Error() string {
+	message := fmt.Sprintf("%s is locked by %s since %s (expires %s)",
+		e.Lock.Key, e.Lock.Owner, e.Lock.CreatedAt.Local().Format("2006-01-02 15:04:05"), e.Lock.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+	if e.Lock.Reason != "" {
+		message = fmt.Sprintf("%s: %s", message, e.Lock.Reason)
+	}
+	return message
+}
+
+//New Return a lock on key for owner, valid for ttl, with a new random token.
+//owner is only displayed, two acquisitions of the same owner exclude each other.
+func New(key, owner, reason, command string, ttl time.Duration) *Lock {
+	now := time.Now().UTC()
+	return &Lock{
+		Key:       key,
+		Token:     newToken(),
+		Owner:     owner,
+		Reason:    reason,
+		Command:   command,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+//Key Return the lock key of an environment deployed on an ECS cluster
+func Key(env, cluster string) string {
+	return fmt.Sprintf("%s/%s", env, cluster)
+}
+
+//Expired tell if the lock can be taken over
+func (lock *Lock) Expired() bool {
+	return time.Now().After(lock.ExpiresAt)
+}
+
+//DefaultOwner Return user@host
+func DefaultOwner() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return name
+	}
+	return fmt.Sprintf("%s@%s", name, host)
+}
+
+//newToken Return a random token identifying an acquisition
+func newToken() string {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	}
+	return hex.EncodeToString(token)
+}
@@ -0,0 +1,109 @@
+package gtdlock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//FileBackend store each lock as a json file of Dir.
+//It only protects releases made from the same machine (or a shared directory).
+type FileBackend struct {
+	Dir string
+}
+
+func (backend *FileBackend) path(key string) string {
+	return filepath.Join(backend.Dir, fmt.Sprintf("%s.json", strings.ReplaceAll(key, "/", "_")))
+}
+
+//Acquire create the lock file, taking over an expired lock
+func (backend *FileBackend) Acquire(lock *Lock) error {
+	if err := os.MkdirAll(backend.Dir, 0700); err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(backend.path(lock.Key), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			defer f.Close()
+			_, err = f.Write(content)
+			return err
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+
+		current, err := backend.Get(lock.Key)
+		if err != nil {
+			return err
+		}
+		if current != nil && !current.Expired() {
+			return &LockedError{Lock: current}
+		}
+		if err := os.Remove(backend.path(lock.Key)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("could not acquire lock %s", lock.Key)
+}
+
+//Release remove the lock file
+func (backend *FileBackend) Release(key, token string, force bool) error {
+	current, err := backend.Get(key)
+	if err != nil || current == nil {
+		return err
+	}
+	if !force && current.Token != token {
+		return &LockedError{Lock: current}
+	}
+	return os.Remove(backend.path(key))
+}
+
+//Refresh rewrite the lock file with the new expiration of lock
+func (backend *FileBackend) Refresh(lock *Lock) error {
+	current, err := backend.Get(lock.Key)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("lock %s was released", lock.Key)
+	}
+	if current.Token != lock.Token {
+		return &LockedError{Lock: current}
+	}
+
+	content, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	// replace the file at once, a reader never sees it half written
+	tmp := fmt.Sprintf("%s.%s", backend.path(lock.Key), lock.Token)
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, backend.path(lock.Key))
+}
+
+//Get read the lock file
+func (backend *FileBackend) Get(key string) (*Lock, error) {
+	content, err := os.ReadFile(backend.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	lock := &Lock{}
+	if err := json.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("invalid lock file %s: %v", backend.path(key), err)
+	}
+	return lock, nil
+}
@@ -0,0 +1,128 @@
+package gtdlock
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFileBackendAcquire(t *testing.T) {
+	backend := &FileBackend{Dir: t.TempDir()}
+	key := Key("rct", "cluster")
+
+	first := New(key, "alice@ci", "hotfix", "deploy", time.Hour)
+	if err := backend.Acquire(first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the same owner does not take over its own lock
+	second := New(key, "alice@ci", "", "deploy", time.Hour)
+	err := backend.Acquire(second)
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected a LockedError, got %v", err)
+	}
+	if locked.Lock.Token != first.Token || locked.Lock.Reason != "hotfix" {
+		t.Errorf("expected the first lock in the error, got %+v", locked.Lock)
+	}
+
+	// another key is not locked
+	if err := backend.Acquire(New(Key("prd", "cluster"), "bob@ci", "", "deploy", time.Hour)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFileBackendAcquireExpired(t *testing.T) {
+	backend := &FileBackend{Dir: t.TempDir()}
+	key := Key("rct", "cluster")
+
+	if err := backend.Acquire(New(key, "alice@ci", "", "deploy", -time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lock := New(key, "bob@ci", "", "deploy", time.Hour)
+	if err := backend.Acquire(lock); err != nil {
+		t.Fatalf("expected to take over the expired lock, got %v", err)
+	}
+	current, err := backend.Get(key)
+	if err != nil || current == nil || current.Token != lock.Token {
+		t.Errorf("expected the lock of bob@ci, got %+v (%v)", current, err)
+	}
+}
+
+func TestFileBackendRelease(t *testing.T) {
+	backend := &FileBackend{Dir: t.TempDir()}
+	key := Key("rct", "cluster")
+
+	// nothing to release
+	if err := backend.Release(key, "token", false); err != nil {
+		t.Errorf("unexpected error releasing a missing lock: %v", err)
+	}
+
+	lock := New(key, "alice@ci", "", "deploy", time.Hour)
+	if err := backend.Acquire(lock); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var locked *LockedError
+	if err := backend.Release(key, "another-token", false); !errors.As(err, &locked) {
+		t.Errorf("expected a LockedError with the wrong token, got %v", err)
+	}
+	if current, _ := backend.Get(key); current == nil {
+		t.Fatal("the lock was released with the wrong token")
+	}
+
+	if err := backend.Release(key, lock.Token, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if current, _ := backend.Get(key); current != nil {
+		t.Errorf("expected no lock, got %+v", current)
+	}
+}
+
+func TestFileBackendForceRelease(t *testing.T) {
+	backend := &FileBackend{Dir: t.TempDir()}
+	key := Key("rct", "cluster")
+
+	if err := backend.Acquire(New(key, "alice@ci", "", "deploy", time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := backend.Release(key, "", true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if current, _ := backend.Get(key); current != nil {
+		t.Errorf("expected no lock, got %+v", current)
+	}
+}
+
+func TestFileBackendRefresh(t *testing.T) {
+	backend := &FileBackend{Dir: t.TempDir()}
+	key := Key("rct", "cluster")
+
+	lock := New(key, "alice@ci", "", "deploy", time.Minute)
+	if err := backend.Acquire(lock); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lock.ExpiresAt = time.Now().UTC().Add(time.Hour)
+	if err := backend.Refresh(lock); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current, err := backend.Get(key)
+	if err != nil || current == nil || !current.ExpiresAt.Equal(lock.ExpiresAt) {
+		t.Errorf("expected the lock to expire at %s, got %+v (%v)", lock.ExpiresAt, current, err)
+	}
+
+	other := New(key, "bob@ci", "", "deploy", time.Hour)
+	var locked *LockedError
+	if err := backend.Refresh(other); !errors.As(err, &locked) {
+		t.Errorf("expected a LockedError refreshing a lock held by someone else, got %v", err)
+	}
+
+	if err := backend.Release(key, lock.Token, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := backend.Refresh(lock); err == nil {
+		t.Error("expected an error refreshing a released lock")
+	}
+}
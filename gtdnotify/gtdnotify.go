@@ -0,0 +1,180 @@
+package gtdnotify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	//EventStart is sent before the services are updated
+	EventStart = "start"
+	//EventSuccess is sent when every service was deployed
+	EventSuccess = "success"
+	//EventFailure is sent when at least one service failed
+	EventFailure = "failure"
+)
+
+type (
+	//Change is the outcome of a service in a deploy
+	Change struct {
+		Service           string `json:"service"`
+		OldTaskDefinition string `json:"old_task_definition,omitempty"`
+		NewTaskDefinition string `json:"new_task_definition,omitempty"`
+		OldImage          string `json:"old_image,omitempty"`
+		NewImage          string `json:"new_image,omitempty"`
+		Status            string `json:"status,omitempty"`
+		Error             string `json:"error,omitempty"`
+	}
+
+	//Event summarize a deploy
+	Event struct {
+		Kind    string    `json:"event"`
+		Action  string    `json:"action"`
+		Env     string    `json:"env"`
+		Cluster string    `json:"cluster"`
+		User    string    `json:"user"`
+		Reason  string    `json:"reason,omitempty"`
+		Time    time.Time `json:"time"`
+		Changes []Change  `json:"services"`
+	}
+
+	//Sink is a webhook receiving events.
+	//Type is webhook (generic JSON, default), slack or teams.
+	//Events filter the kinds sent, every kind when empty.
+	Sink struct {
+		Type   string
+		URL    string
+		Events []string
+	}
+
+	//Notifier post events to its sinks
+	Notifier struct {
+		Sinks  []Sink
+		Client *http.Client
+	}
+)
+
+//NewNotifier Return a notifier posting to sinks with a 10s timeout
+func NewNotifier(sinks []Sink) *Notifier {
+	return &Notifier{
+		Sinks:  sinks,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+//Accept tell if the sink wants events of kind
+func (sink Sink) Accept(kind string) bool {
+	if len(sink.Events) == 0 {
+		return true
+	}
+	for _, event := range sink.Events {
+		if strings.EqualFold(event, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+//Notify post event to every sink accepting it.
+//A failing sink does not prevent the others from being notified.
+func (notifier *Notifier) Notify(event Event) error {
+	failures := make([]string, 0)
+	for _, sink := range notifier.Sinks {
+		if !sink.Accept(event.Kind) {
+			continue
+		}
+		if err := notifier.post(sink, event); err != nil {
+			failures = append(failures, fmt.Sprintf("%s %s: %v", sink.Type, sink.URL, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("notification failed: %s", strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+//Payload Return the body posted to sink for event
+func Payload(sink Sink, event Event) (interface{}, error) {
+	switch strings.ToLower(sink.Type) {
+	case "", "webhook", "json":
+		return event, nil
+	case "slack":
+		return slackPayload(event), nil
+	case "teams":
+		return teamsPayload(event), nil
+	default:
+		return nil, fmt.Errorf("unknown notification type %s", sink.Type)
+	}
+}
+
+func (notifier *Notifier) post(sink Sink, event Event) error {
+	payload, err := Payload(sink, event)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	client := notifier.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Post(sink.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+//Title Return a one line summary of event
+func Title(event Event) string {
+	var state string
+	switch event.Kind {
+	case EventStart:
+		state = "started"
+	case EventSuccess:
+		state = "succeeded"
+	case EventFailure:
+		state = "FAILED"
+	default:
+		state = event.Kind
+	}
+
+	title := fmt.Sprintf("%s of %s (%s) %s by %s", event.Action, event.Env, event.Cluster, state, event.User)
+	if event.Reason != "" {
+		title = fmt.Sprintf("%s: %s", title, event.Reason)
+	}
+	return title
+}
+
+//ChangeLine Return a one line summary of a service change
+func ChangeLine(change Change) string {
+	line := change.Service
+	if change.NewImage != "" && change.NewImage != change.OldImage {
+		line = fmt.Sprintf("%s %s -> %s", line, change.OldImage, change.NewImage)
+	}
+	if change.NewTaskDefinition != "" {
+		line = fmt.Sprintf("%s (%s)", line, change.NewTaskDefinition)
+	}
+	if change.Status != "" {
+		line = fmt.Sprintf("%s [%s]", line, change.Status)
+	}
+	if change.Error != "" {
+		line = fmt.Sprintf("%s: %s", line, change.Error)
+	}
+	return line
+}
@@ -0,0 +1,49 @@
+package gtdnotify
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	slackMessage struct {
+		Text        string            `json:"text"`
+		Attachments []slackAttachment `json:"attachments,omitempty"`
+	}
+
+	slackAttachment struct {
+		Color string `json:"color"`
+		Text  string `json:"text"`
+	}
+)
+
+//slackPayload format event for a Slack incoming webhook
+func slackPayload(event Event) slackMessage {
+	message := slackMessage{Text: Title(event)}
+	if len(event.Changes) == 0 {
+		return message
+	}
+
+	lines := make([]string, 0, len(event.Changes))
+	for _, change := range event.Changes {
+		lines = append(lines, fmt.Sprintf("• %s", ChangeLine(change)))
+	}
+
+	message.Attachments = []slackAttachment{{
+		Color: color(event.Kind),
+		Text:  strings.Join(lines, "\n"),
+	}}
+	return message
+}
+
+//color Return the hex color of an event kind
+func color(kind string) string {
+	switch kind {
+	case EventSuccess:
+		return "#2eb886"
+	case EventFailure:
+		return "#a30200"
+	default:
+		return "#439fe0"
+	}
+}
@@ -0,0 +1,48 @@
+package gtdnotify
+
+import "strings"
+
+type (
+	teamsCard struct {
+		Type       string         `json:"@type"`
+		Context    string         `json:"@context"`
+		ThemeColor string         `json:"themeColor"`
+		Summary    string         `json:"summary"`
+		Title      string         `json:"title"`
+		Sections   []teamsSection `json:"sections,omitempty"`
+	}
+
+	teamsSection struct {
+		Facts []teamsFact `json:"facts"`
+	}
+
+	teamsFact struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+)
+
+//teamsPayload format event as a Microsoft Teams MessageCard
+func teamsPayload(event Event) teamsCard {
+	title := Title(event)
+	card := teamsCard{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		ThemeColor: strings.TrimPrefix(color(event.Kind), "#"),
+		Summary:    title,
+		Title:      title,
+	}
+	if len(event.Changes) == 0 {
+		return card
+	}
+
+	facts := make([]teamsFact, 0, len(event.Changes))
+	for _, change := range event.Changes {
+		facts = append(facts, teamsFact{
+			Name:  change.Service,
+			Value: strings.TrimSpace(strings.TrimPrefix(ChangeLine(change), change.Service)),
+		})
+	}
+	card.Sections = []teamsSection{{Facts: facts}}
+	return card
+}
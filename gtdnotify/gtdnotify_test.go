@@ -0,0 +1,244 @@
+package gtdnotify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//receiver is a local webhook keeping the bodies it receives
+type receiver struct {
+	mu     sync.Mutex
+	bodies [][]byte
+	status int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if req.Header.Get("Content-Type") == "application/json" {
+		r.bodies = append(r.bodies, body)
+	}
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+		_, _ = w.Write([]byte("boom"))
+	}
+}
+
+func (r *receiver) received() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bodies
+}
+
+func newReceiver(t *testing.T, status int) (*receiver, string) {
+	r := &receiver{status: status}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return r, server.URL
+}
+
+func testEvent(kind string) Event {
+	event := Event{
+		Kind:    kind,
+		Action:  "deploy",
+		Env:     "rct",
+		Cluster: "cluster-rct",
+		User:    "releaser",
+		Reason:  "hotfix",
+		Time:    time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		Changes: []Change{{
+			Service:           "svc-hapi",
+			OldTaskDefinition: "hapi:1",
+			OldImage:          "gutenbergtech/hapi:1.0",
+			Status:            "PENDING",
+		}},
+	}
+	switch kind {
+	case EventSuccess:
+		event.Changes[0].NewTaskDefinition = "hapi:2"
+		event.Changes[0].NewImage = "gutenbergtech/hapi:1.1"
+		event.Changes[0].Status = "STABLE"
+	case EventFailure:
+		event.Changes[0].NewTaskDefinition = "hapi:2"
+		event.Changes[0].NewImage = "gutenbergtech/hapi:1.1"
+		event.Changes[0].Status = "FAILED"
+		event.Changes[0].Error = "deployment failed"
+	}
+	return event
+}
+
+func TestNotifyWebhook(t *testing.T) {
+	for _, kind := range []string{EventStart, EventSuccess, EventFailure} {
+		t.Run(kind, func(t *testing.T) {
+			r, url := newReceiver(t, 0)
+			event := testEvent(kind)
+
+			if err := NewNotifier([]Sink{{URL: url}}).Notify(event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			bodies := r.received()
+			if len(bodies) != 1 {
+				t.Fatalf("expected 1 json post, got %d", len(bodies))
+			}
+
+			var got Event
+			if err := json.Unmarshal(bodies[0], &got); err != nil {
+				t.Fatalf("invalid payload %s: %v", bodies[0], err)
+			}
+			if got.Kind != kind || got.Env != "rct" || got.Cluster != "cluster-rct" || got.User != "releaser" || !got.Time.Equal(event.Time) {
+				t.Errorf("unexpected event %+v", got)
+			}
+			if len(got.Changes) != 1 || got.Changes[0] != event.Changes[0] {
+				t.Errorf("expected changes %+v, got %+v", event.Changes, got.Changes)
+			}
+		})
+	}
+}
+
+func TestNotifySlack(t *testing.T) {
+	colors := map[string]string{EventStart: "#439fe0", EventSuccess: "#2eb886", EventFailure: "#a30200"}
+	titles := map[string]string{
+		EventStart:   "deploy of rct (cluster-rct) started by releaser: hotfix",
+		EventSuccess: "deploy of rct (cluster-rct) succeeded by releaser: hotfix",
+		EventFailure: "deploy of rct (cluster-rct) FAILED by releaser: hotfix",
+	}
+	lines := map[string]string{
+		EventStart:   "• svc-hapi [PENDING]",
+		EventSuccess: "• svc-hapi gutenbergtech/hapi:1.0 -> gutenbergtech/hapi:1.1 (hapi:2) [STABLE]",
+		EventFailure: "• svc-hapi gutenbergtech/hapi:1.0 -> gutenbergtech/hapi:1.1 (hapi:2) [FAILED]: deployment failed",
+	}
+
+	for _, kind := range []string{EventStart, EventSuccess, EventFailure} {
+		t.Run(kind, func(t *testing.T) {
+			r, url := newReceiver(t, 0)
+			if err := NewNotifier([]Sink{{Type: "slack", URL: url}}).Notify(testEvent(kind)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			bodies := r.received()
+			if len(bodies) != 1 {
+				t.Fatalf("expected 1 json post, got %d", len(bodies))
+			}
+
+			var got slackMessage
+			if err := json.Unmarshal(bodies[0], &got); err != nil {
+				t.Fatalf("invalid payload %s: %v", bodies[0], err)
+			}
+			if got.Text != titles[kind] {
+				t.Errorf("expected text %q, got %q", titles[kind], got.Text)
+			}
+			if len(got.Attachments) != 1 {
+				t.Fatalf("expected 1 attachment, got %+v", got.Attachments)
+			}
+			if got.Attachments[0].Color != colors[kind] {
+				t.Errorf("expected color %s, got %s", colors[kind], got.Attachments[0].Color)
+			}
+			if got.Attachments[0].Text != lines[kind] {
+				t.Errorf("expected attachment %q, got %q", lines[kind], got.Attachments[0].Text)
+			}
+		})
+	}
+}
+
+func TestNotifyTeams(t *testing.T) {
+	colors := map[string]string{EventStart: "439fe0", EventSuccess: "2eb886", EventFailure: "a30200"}
+
+	for _, kind := range []string{EventStart, EventSuccess, EventFailure} {
+		t.Run(kind, func(t *testing.T) {
+			r, url := newReceiver(t, 0)
+			event := testEvent(kind)
+			if err := NewNotifier([]Sink{{Type: "teams", URL: url}}).Notify(event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			bodies := r.received()
+			if len(bodies) != 1 {
+				t.Fatalf("expected 1 json post, got %d", len(bodies))
+			}
+
+			var got teamsCard
+			if err := json.Unmarshal(bodies[0], &got); err != nil {
+				t.Fatalf("invalid payload %s: %v", bodies[0], err)
+			}
+			if got.Type != "MessageCard" || got.Context != "http://schema.org/extensions" {
+				t.Errorf("not a MessageCard: %s", bodies[0])
+			}
+			if got.ThemeColor != colors[kind] {
+				t.Errorf("expected color %s, got %s", colors[kind], got.ThemeColor)
+			}
+			if got.Title != Title(event) || got.Summary != Title(event) {
+				t.Errorf("expected title %q, got %q / %q", Title(event), got.Title, got.Summary)
+			}
+			if len(got.Sections) != 1 || len(got.Sections[0].Facts) != 1 {
+				t.Fatalf("expected 1 fact, got %+v", got.Sections)
+			}
+			fact := got.Sections[0].Facts[0]
+			expected := strings.TrimSpace(strings.TrimPrefix(ChangeLine(event.Changes[0]), "svc-hapi"))
+			if fact.Name != "svc-hapi" || fact.Value != expected {
+				t.Errorf("expected fact svc-hapi=%q, got %+v", expected, fact)
+			}
+		})
+	}
+}
+
+func TestNotifyEventsFilter(t *testing.T) {
+	r, url := newReceiver(t, 0)
+	notifier := NewNotifier([]Sink{{URL: url, Events: []string{"Failure"}}})
+
+	for _, kind := range []string{EventStart, EventSuccess, EventFailure} {
+		if err := notifier.Notify(testEvent(kind)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	bodies := r.received()
+	if len(bodies) != 1 {
+		t.Fatalf("expected only the failure event, got %d posts", len(bodies))
+	}
+	var got Event
+	if err := json.Unmarshal(bodies[0], &got); err != nil || got.Kind != EventFailure {
+		t.Errorf("expected a failure event, got %s", bodies[0])
+	}
+}
+
+func TestNotifyErrorStatus(t *testing.T) {
+	failing, failingURL := newReceiver(t, http.StatusInternalServerError)
+	working, workingURL := newReceiver(t, 0)
+
+	err := NewNotifier([]Sink{
+		{Type: "slack", URL: failingURL},
+		{URL: workingURL},
+	}).Notify(testEvent(EventFailure))
+
+	if err == nil {
+		t.Fatal("expected an error on a 500 response")
+	}
+	for _, part := range []string{"slack", failingURL, "500 Internal Server Error", "boom"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("expected %q in error %q", part, err.Error())
+		}
+	}
+	if len(failing.received()) != 1 {
+		t.Errorf("expected the failing sink to be called once, got %d", len(failing.received()))
+	}
+	// a failing sink does not prevent the others from being notified
+	if len(working.received()) != 1 {
+		t.Errorf("expected the other sink to be notified, got %d posts", len(working.received()))
+	}
+}
+
+func TestNotifyUnknownType(t *testing.T) {
+	r, url := newReceiver(t, 0)
+	err := NewNotifier([]Sink{{Type: "pager", URL: url}}).Notify(testEvent(EventStart))
+	if err == nil || !strings.Contains(err.Error(), "unknown notification type pager") {
+		t.Errorf("expected an unknown type error, got %v", err)
+	}
+	if len(r.received()) != 0 {
+		t.Errorf("expected nothing posted, got %d posts", len(r.received()))
+	}
+}